@@ -1,10 +1,20 @@
 package main
 
 import (
+	"os"
+
 	"maintenance/maintenance"
 )
 
 func main() {
-	command := maintenance.ReadCommand()
-	command.Run()
+	command, err := maintenance.ReadCommand()
+	if err != nil {
+		maintenance.DefaultLogger().Error("invalid command", "err", err)
+		os.Exit(maintenance.ExitCode(err))
+	}
+
+	if err := command.Run(); err != nil {
+		maintenance.DefaultLogger().Error("command failed", "err", err)
+		os.Exit(maintenance.ExitCode(err))
+	}
 }
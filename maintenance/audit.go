@@ -0,0 +1,201 @@
+package maintenance
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record of a change made to Statuspage, written as a line of JSON
+type AuditRecord struct {
+	Time         time.Time   `json:"time"`
+	RunId        string      `json:"runId"`
+	Operator     string      `json:"operator"`
+	ConfigCommit string      `json:"configCommit,omitempty"` // git commit of statuspage file
+	PageId       string      `json:"pageId"`
+	Operation    string      `json:"operation"`
+	IncidentId   string      `json:"incidentId,omitempty"`
+	Request      interface{} `json:"request,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// Writer of audit records, shared by repositories of all pages in a run
+type AuditLog struct {
+	mu           sync.Mutex
+	w            io.Writer
+	RunId        string
+	Operator     string
+	ConfigCommit string
+	now          func() time.Time
+	err          error // the first error of writing. No more changes are made after it.
+}
+
+// Incident deleted by the tool, written as request of delete
+type auditDeletedIncident struct {
+	Name           string    `json:"name"`
+	ScheduledFor   time.Time `json:"scheduled_for"`
+	ScheduledUntil time.Time `json:"scheduled_until"`
+	ComponentIds   []string  `json:"component_ids"`
+}
+
+func NewAuditLog(w io.Writer, operator string, configCommit string) *AuditLog {
+	return &AuditLog{
+		w:            w,
+		RunId:        newRunId(),
+		Operator:     operator,
+		ConfigCommit: configCommit,
+		now:          time.Now,
+	}
+}
+
+// open the file to append audit records.
+// The returned function closes the file.
+func openAuditLog(fileName string, operator string, configCommit string) (*AuditLog, func() error, error) {
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, configErrorf("failed to open audit log: %s", err)
+	}
+	return NewAuditLog(f, operator, configCommit), f.Close, nil
+}
+
+// write the record. Error is returned if it fails, and also after that.
+func (a *AuditLog) write(record AuditRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	record.Time = a.now()
+	record.RunId = a.RunId
+	record.Operator = a.Operator
+	record.ConfigCommit = a.ConfigCommit
+
+	line, err := json.Marshal(record)
+	if err == nil {
+		_, err = a.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		logger.Error("failed to write audit log", "operation", record.Operation, "incidentId", record.IncidentId, "err", err)
+		if a.err == nil {
+			a.err = fmt.Errorf("failed to write audit log: %w", err)
+		}
+	}
+	return a.err
+}
+
+// return error if writing has failed, so that changes are not made without audit records
+func (a *AuditLog) check() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// return random id of a run
+func newRunId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// return operator of changes. `operator` option is used if it's specified, or the user of OS.
+func operatorOf(operator string) string {
+	if operator != "" {
+		return operator
+	}
+	if user := os.Getenv("MAINTENANCE_OPERATOR"); user != "" {
+		return user
+	}
+	return os.Getenv("USER")
+}
+
+// return git commit of the repository which has the file. Empty string is returned if it's not in git.
+func configCommitOf(fileName string) string {
+	out, err := exec.Command("git", "-C", filepath.Dir(fileName), "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// StatuspageRepository which writes audit records of changes made by the repository
+type StatuspageAuditRepository struct {
+	repository StatuspageRepository
+	auditLog   *AuditLog
+	pageId     string
+}
+
+func createStatuspageAuditRepository(repository StatuspageRepository, auditLog *AuditLog, pageId string) *StatuspageAuditRepository {
+	return &StatuspageAuditRepository{repository: repository, auditLog: auditLog, pageId: pageId}
+}
+
+// Changes are not made after writing of audit log fails.
+// When the change is made but its record is not written, the error of audit log is returned.
+func (s *StatuspageAuditRepository) Add(data StatuspageCreateIncidentRequest) (StatuspageIncident, error) {
+	if err := s.auditLog.check(); err != nil {
+		return StatuspageIncident{}, err
+	}
+	incident, err := s.repository.Add(data)
+	return incident, s.write(Operation_Add, incident.Id, data, err)
+}
+
+func (s *StatuspageAuditRepository) Delete(incident StatuspageIncident) error {
+	if err := s.auditLog.check(); err != nil {
+		return err
+	}
+	err := s.repository.Delete(incident)
+	return s.write(Operation_Delete, incident.Id, auditDeletedIncident{
+		Name:           incident.Name,
+		ScheduledFor:   incident.ScheduledFor,
+		ScheduledUntil: incident.ScheduledUntil,
+		ComponentIds:   incident.componentIds(),
+	}, err)
+}
+
+func (s *StatuspageAuditRepository) Update(incident StatuspageIncident, data StatuspageUpdateIncidentRequest) error {
+	if err := s.auditLog.check(); err != nil {
+		return err
+	}
+	err := s.repository.Update(incident, data)
+	return s.write(Operation_Update, incident.Id, data, err)
+}
+
+func (s *StatuspageAuditRepository) FindAllScheduledIncidents(page int, perPage int) ([]StatuspageIncident, error) {
+	return s.repository.FindAllScheduledIncidents(page, perPage)
+}
+
+func (s *StatuspageAuditRepository) FindAllActiveMaintenances(page int, perPage int) ([]StatuspageIncident, error) {
+	return s.repository.FindAllActiveMaintenances(page, perPage)
+}
+
+func (s *StatuspageAuditRepository) FindIncident(incidentId string) (*StatuspageIncident, error) {
+	return s.repository.FindIncident(incidentId)
+}
+
+func (s *StatuspageAuditRepository) FindAllComponents(page int, perPage int) ([]StatuspageComponnet, error) {
+	return s.repository.FindAllComponents(page, perPage)
+}
+
+// write the record of the operation, and return err of the operation, or error of audit log
+func (s *StatuspageAuditRepository) write(operation string, incidentId string, request interface{}, err error) error {
+	record := AuditRecord{
+		PageId:     s.pageId,
+		Operation:  operation,
+		IncidentId: incidentId,
+		Request:    request,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if auditErr := s.auditLog.write(record); err == nil {
+		err = auditErr
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package maintenance
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// StatuspageRepository whose Delete fails
+type failingDeleteRepository struct {
+	fakeRepository
+}
+
+func (r *failingDeleteRepository) Delete(incident StatuspageIncident) error {
+	return errors.New("not found")
+}
+
+func TestStatuspageAuditRepository(t *testing.T) {
+	var buf bytes.Buffer
+	auditLog := NewAuditLog(&buf, "alice", "abc123")
+	auditLog.now = func() time.Time { return timeOf(2020, 1, 1, 10, 0) }
+
+	repository := createStatuspageAuditRepository(&failingDeleteRepository{}, auditLog, "page1")
+	if _, err := repository.Add(StatuspageCreateIncidentRequest{Incident: StatuspageIncidentRequest{Name: "maintenance"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.Update(StatuspageIncident{Id: "1"}, StatuspageUpdateIncidentRequest{Incident: StatuspageIncidentUpdate{Name: "new"}}); err != nil {
+		t.Fatal(err)
+	}
+	deleted := StatuspageIncident{
+		Id:             "2",
+		Name:           "old maintenance",
+		Components:     []StatuspageComponnet{{Id: "c1"}},
+		ScheduledFor:   timeOf(2020, 1, 2, 10, 0),
+		ScheduledUntil: timeOf(2020, 1, 2, 11, 0),
+	}
+	if err := repository.Delete(deleted); err == nil {
+		t.Fatal("error of the repository should be returned")
+	}
+	if _, err := repository.FindAllScheduledIncidents(1, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []AuditRecord{
+		{Operation: Operation_Add, IncidentId: "added1"},
+		{Operation: Operation_Update, IncidentId: "1"},
+		{Operation: Operation_Delete, IncidentId: "2", Error: "not found"},
+	}
+
+	records := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		record := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid record: %s", scanner.Text())
+		}
+		records = append(records, record)
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("exp:%v records, actual:%v", len(expected), len(records))
+	}
+
+	for idx, exp := range expected {
+		actual := records[idx]
+		if actual["operation"] != exp.Operation || actual["incidentId"] != exp.IncidentId || (actual["error"] != nil && actual["error"] != exp.Error) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, exp, actual)
+		}
+		if actual["operator"] != "alice" || actual["configCommit"] != "abc123" || actual["pageId"] != "page1" || actual["runId"] != auditLog.RunId {
+			t.Errorf("test(%v): unexpected record: %v", idx+1, actual)
+		}
+		if actual["time"] != "2020-01-01T10:00:00+09:00" {
+			t.Errorf("test(%v): unexpected time: %v", idx+1, actual["time"])
+		}
+	}
+	if records[0]["request"] == nil || records[1]["request"] == nil {
+		t.Errorf("request should be written for add and update: %v", records)
+	}
+	// deleted incident is written as request of delete
+	request, _ := records[2]["request"].(map[string]interface{})
+	if request["name"] != "old maintenance" || request["scheduled_for"] != "2020-01-02T10:00:00+09:00" ||
+		request["scheduled_until"] != "2020-01-02T11:00:00+09:00" || fmt.Sprint(request["component_ids"]) != "[c1]" {
+		t.Errorf("unexpected request of delete: %v", records[2]["request"])
+	}
+}
+
+// Writer which always fails
+type failingWriter struct{}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestStatuspageAuditRepositoryWriteFailure(t *testing.T) {
+	repository := &fakeRepository{}
+	auditRepository := createStatuspageAuditRepository(repository, NewAuditLog(failingWriter{}, "alice", ""), "page1")
+
+	// the change is made, but the error of audit log is returned
+	if _, err := auditRepository.Add(StatuspageCreateIncidentRequest{}); err == nil {
+		t.Error("error of audit log should be returned")
+	}
+	// no more changes are made
+	if err := auditRepository.Update(StatuspageIncident{Id: "1"}, StatuspageUpdateIncidentRequest{}); err == nil {
+		t.Error("error of audit log should be returned")
+	}
+	if err := auditRepository.Delete(StatuspageIncident{Id: "1"}); err == nil {
+		t.Error("error of audit log should be returned")
+	}
+	if len(repository.added) != 1 || len(repository.updated) != 0 || len(repository.deleted) != 0 {
+		t.Errorf("changes should not be made after failure: %v %v %v", repository.added, repository.updated, repository.deleted)
+	}
+}
@@ -2,52 +2,274 @@ package maintenance
 
 import (
 	"flag"
-	"log"
 	"os"
 	"time"
 )
 
 type Command interface {
-	Run()
+	Run() error
 }
 
 var dateLayout = "2006-01-02"
+var dateTimeLayout = "2006-01-02 15:04"
 
 var loc, _ = time.LoadLocation("Asia/Tokyo")
 
 // コマンドライン引数から、実行するコマンド情報を読み込む
-func ReadCommand() Command {
+func ReadCommand() (Command, error) {
 	accessToken := os.Getenv("STATUSPAGE_API_KEY")
 
 	recurringCmd := flag.NewFlagSet("recurring", flag.ExitOnError)
-	recurringScheduleFilename := recurringCmd.String("schedule", "", "file to load maintenance schedule information")
+	recurringLog := addLogFlags(recurringCmd)
+	recurringOptions := addRecurringFlags(recurringCmd, accessToken)
 	recurringFrom := recurringCmd.String("from", "", "first date to create schedule")
 	recurringDay := recurringCmd.Int("day", 0, "days of terms to create schedule")
-	recurringStatuspageFilename := recurringCmd.String("statuspage", "", "file to load configuration of statuspage")
-	recurringDryRun := recurringCmd.Bool("dryRun", false, "is dryRun")
+
+	daemonCmd := flag.NewFlagSet("daemon", flag.ExitOnError)
+	daemonLog := addLogFlags(daemonCmd)
+	daemonOptions := addRecurringFlags(daemonCmd, accessToken)
+	daemonDay := daemonCmd.Int("day", 30, "days of terms to create schedule, starting from today")
+	daemonInterval := daemonCmd.Duration("interval", time.Hour, "interval to register maintenances. maintenances are registered also when the files are changed")
+	daemonMetricsAddr := daemonCmd.String("metrics-addr", "", "address to serve metrics in Prometheus format. e.g. :9090")
+
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	serveLog := addLogFlags(serveCmd)
+	serveOptions := addRecurringFlags(serveCmd, accessToken)
+	serveAddr := serveCmd.String("addr", ":8080", "address to listen")
+	serveDay := serveCmd.Int("day", 30, "default days of terms to create schedule, starting from today")
+
+	driftCmd := flag.NewFlagSet("drift", flag.ExitOnError)
+	driftLog := addLogFlags(driftCmd)
+	driftOptions := addRecurringFlags(driftCmd, accessToken)
+	driftFrom := driftCmd.String("from", "", "first date to compare maintenances (default: today)")
+	driftDay := driftCmd.Int("day", 30, "days of terms to compare maintenances")
+	driftFix := driftCmd.Bool("fix", false, "register maintenances by the schedule when drifts are found")
+
+	onceCmd := flag.NewFlagSet("once", flag.ExitOnError)
+	onceLog := addLogFlags(onceCmd)
+	onceOptions := addRecurringFlags(onceCmd, accessToken)
+	onceService := onceCmd.String("service", "", "service of maintenance in statuspage file")
+	onceTitle := onceCmd.String("title", "", "title of maintenance")
+	onceBody := onceCmd.String("body", "", "body of maintenance")
+	onceStart := onceCmd.String("start", "", "start time of maintenance. e.g. \"2020-01-01 10:00\" or RFC3339")
+	onceEnd := onceCmd.String("end", "", "end time of maintenance. e.g. \"2020-01-01 11:00\" or RFC3339")
+	onceDuration := onceCmd.Duration("duration", 0, "duration of maintenance instead of end. e.g. 30m")
+
+	componentsCmd := flag.NewFlagSet("components", flag.ExitOnError)
+	componentsLog := addLogFlags(componentsCmd)
+	componentsPage := componentsCmd.String("page", "", "pageId or name of page to list components")
+	componentsStatuspageFilename := componentsCmd.String("statuspage", "", "file to load configuration of statuspage. componentIds which no longer exist are reported")
+	componentsFormat := componentsCmd.String("format", ComponentsFormat_Table, "output format: table or yaml")
 
 	flag.Parse()
 
+	if len(os.Args) < 2 {
+		return nil, configErrorf("command is not specified")
+	}
+
 	switch os.Args[1] {
 
 	case "recurring":
 		recurringCmd.Parse(os.Args[2:])
+		if err := recurringLog(); err != nil {
+			return nil, err
+		}
+		command, err := recurringOptions()
+		if err != nil {
+			return nil, err
+		}
 		fromDate, err := time.ParseInLocation(dateLayout, *recurringFrom, loc)
 		if err != nil {
-			log.Fatalf("[ERROR] invalid fromDate: %s", err)
+			return nil, configErrorf("invalid fromDate: %s", err)
 		}
+		command.FromDate = fromDate
+		command.ToDate = fromDate.AddDate(0, 0, *recurringDay-1)
+		return &command, nil
 
-		return &RecurringCommand{
-			isDryRun:           *recurringDryRun,
-			ScheduleFilename:   *recurringScheduleFilename,
-			StatuspageFilename: *recurringStatuspageFilename,
-			FromDate:           fromDate,
-			ToDate:             fromDate.AddDate(0, 0, *recurringDay-1),
-			AccessToken:        accessToken,
+	case "daemon":
+		daemonCmd.Parse(os.Args[2:])
+		if err := daemonLog(); err != nil {
+			return nil, err
+		}
+		command, err := daemonOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		return &DaemonCommand{
+			Recurring:   command,
+			Day:         *daemonDay,
+			Interval:    *daemonInterval,
+			MetricsAddr: *daemonMetricsAddr,
+		}, nil
+
+	case "serve":
+		serveCmd.Parse(os.Args[2:])
+		if err := serveLog(); err != nil {
+			return nil, err
+		}
+		command, err := serveOptions()
+		if err != nil {
+			return nil, err
 		}
 
+		return &ServeCommand{
+			Addr:      *serveAddr,
+			Token:     os.Getenv("MAINTENANCE_API_TOKEN"),
+			Recurring: command,
+			Day:       *serveDay,
+		}, nil
+
+	case "drift":
+		driftCmd.Parse(os.Args[2:])
+		if err := driftLog(); err != nil {
+			return nil, err
+		}
+		command, err := driftOptions()
+		if err != nil {
+			return nil, err
+		}
+		fromDate := dateIn(time.Now())
+		if *driftFrom != "" {
+			fromDate, err = time.ParseInLocation(dateLayout, *driftFrom, loc)
+			if err != nil {
+				return nil, configErrorf("invalid fromDate: %s", err)
+			}
+		}
+		command.FromDate = fromDate
+		command.ToDate = fromDate.AddDate(0, 0, *driftDay-1)
+
+		return &DriftCommand{
+			Recurring: command,
+			Fix:       *driftFix,
+		}, nil
+
+	case "once":
+		onceCmd.Parse(os.Args[2:])
+		if err := onceLog(); err != nil {
+			return nil, err
+		}
+		command, err := onceOptions()
+		if err != nil {
+			return nil, err
+		}
+		start, err := parseOptionalDateTime(*onceStart)
+		if err != nil {
+			return nil, configErrorf("invalid start: %s", err)
+		}
+		end, err := parseOptionalDateTime(*onceEnd)
+		if err != nil {
+			return nil, configErrorf("invalid end: %s", err)
+		}
+		if *onceDuration != 0 {
+			if !end.IsZero() {
+				return nil, configErrorf("end and duration can't be specified together")
+			}
+			end = start.Add(*onceDuration)
+		}
+
+		return &OnceCommand{
+			Recurring: command,
+			Service:   *onceService,
+			Title:     *onceTitle,
+			Body:      *onceBody,
+			Start:     start,
+			End:       end,
+		}, nil
+
+	case "components":
+		componentsCmd.Parse(os.Args[2:])
+		if err := componentsLog(); err != nil {
+			return nil, err
+		}
+
+		return &ComponentsCommand{
+			PageId:             *componentsPage,
+			StatuspageFilename: *componentsStatuspageFilename,
+			Format:             *componentsFormat,
+			AccessToken:        accessToken,
+		}, nil
+
 	default:
-		log.Fatalf("[ERROR] Unknown command is specified")
-		return nil
+		return nil, configErrorf("unknown command is specified: %s", os.Args[1])
+	}
+}
+
+// add options of RecurringCommand to the flag set, and return function to create RecurringCommand by them.
+// FromDate and ToDate are set by each command.
+func addRecurringFlags(cmd *flag.FlagSet, accessToken string) func() (RecurringCommand, error) {
+	scheduleFilename := cmd.String("schedule", "", "file to load maintenance schedule information")
+	statuspageFilename := cmd.String("statuspage", "", "file to load configuration of statuspage")
+	dryRun := cmd.Bool("dryRun", false, "is dryRun")
+	owner := cmd.String("owner", "", "owner of maintenances. maintenances of other owners are not deleted (default: owner of statuspage file)")
+	conflict := cmd.String("conflict", "", "policy when maintenance is overlapped with another maintenance: skip, split, extend-manual or register-anyway (default: conflictPolicy of statuspage file, or skip)")
+	prune := cmd.Bool("prune", true, "delete maintenances which are not in schedule")
+	noPrune := cmd.Bool("no-prune", false, "don't delete maintenances which are not in schedule")
+	pruneFrom := cmd.String("prune-from", "", "first date to delete maintenances which are not in schedule (default: from)")
+	pruneTo := cmd.String("prune-to", "", "last date to delete maintenances which are not in schedule (default: last date of schedule)")
+	updateInProgress := cmd.Bool("update-in-progress", false, "update end time, title, body and components of maintenances in progress")
+	gracePeriod := cmd.Duration("grace-period", 0, "register maintenances which started within the period. e.g. 30m")
+	continueOnError := cmd.Bool("continue-on-error", false, "continue when an operation fails and report results at the end")
+	auditLog := cmd.String("audit-log", "", "file to append audit records of changes made to Statuspage")
+	operator := cmd.String("operator", "", "operator written in audit records (default: MAINTENANCE_OPERATOR or USER environment variable)")
+	state := cmd.String("state", "", "file to record maintenances created by this tool")
+	lock := cmd.String("lock", "", "lock file to prevent concurrent runs")
+
+	return func() (RecurringCommand, error) {
+		pruneFromDate, err := parseOptionalDate(*pruneFrom)
+		if err != nil {
+			return RecurringCommand{}, configErrorf("invalid prune-from: %s", err)
+		}
+		pruneToDate, err := parseOptionalDate(*pruneTo)
+		if err != nil {
+			return RecurringCommand{}, configErrorf("invalid prune-to: %s", err)
+		}
+
+		return RecurringCommand{
+			isDryRun:           *dryRun,
+			ContinueOnError:    *continueOnError,
+			UpdateInProgress:   *updateInProgress,
+			GracePeriod:        *gracePeriod,
+			Owner:              *owner,
+			ConflictPolicy:     *conflict,
+			ScheduleFilename:   *scheduleFilename,
+			StatuspageFilename: *statuspageFilename,
+			AuditLogFilename:   *auditLog,
+			Operator:           *operator,
+			StateFilename:      *state,
+			LockFilename:       *lock,
+			NoPrune:            !*prune || *noPrune,
+			PruneFromDate:      pruneFromDate,
+			PruneToDate:        pruneToDate,
+			AccessToken:        accessToken,
+		}, nil
+	}
+}
+
+// parse date. zero time is returned for empty string.
+func parseOptionalDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(dateLayout, value, loc)
+}
+
+// parse date and time in "2006-01-02 15:04" or RFC3339. zero time is returned for empty string.
+func parseOptionalDateTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(dateTimeLayout, value, loc)
+}
+
+// add options of logs to the flag set, and return function to configure logger by them
+func addLogFlags(cmd *flag.FlagSet) func() error {
+	level := cmd.String("log-level", "info", "level of logs: debug, info, warn or error")
+	format := cmd.String("log-format", LogFormat_Text, "format of logs: text or json")
+	return func() error {
+		return configureLogger(*level, *format)
 	}
 }
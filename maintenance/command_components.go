@@ -0,0 +1,180 @@
+package maintenance
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+const (
+	ComponentsFormat_Table = "table"
+	ComponentsFormat_Yaml  = "yaml"
+)
+
+type ComponentsCommand struct {
+	PageId             string
+	StatuspageFilename string
+	Format             string
+	AccessToken        string
+}
+
+// execute components command
+func (c *ComponentsCommand) Run() error {
+	if c.Format != ComponentsFormat_Table && c.Format != ComponentsFormat_Yaml {
+		return configErrorf("unknown format: %s", c.Format)
+	}
+
+	pages, err := c.pages()
+	if err != nil {
+		return err
+	}
+
+	staleCount := 0
+	for _, page := range pages {
+		repository := createStatuspageRESTRepository(page.StatuspagePageId, page.accessToken(c.AccessToken))
+		components, err := findAllComponents(repository)
+		if err != nil {
+			return fmt.Errorf("FindAllComponents err: %w", err)
+		}
+
+		switch c.Format {
+		case ComponentsFormat_Table:
+			printComponentsTable(os.Stdout, page, components)
+		case ComponentsFormat_Yaml:
+			printComponentsYaml(os.Stdout, page, components)
+		}
+
+		if c.StatuspageFilename != "" {
+			// written to stderr, not to break yaml
+			staleCount += printStaleComponents(os.Stderr, page, components)
+		}
+	}
+
+	if staleCount > 0 {
+		return &StaleComponentError{Count: staleCount, Filename: c.StatuspageFilename}
+	}
+	return nil
+}
+
+// return pages to list components.
+// If pageId is not specified, all pages in statuspage.yaml are returned.
+func (c *ComponentsCommand) pages() ([]StatuspagePage, error) {
+	if c.StatuspageFilename == "" {
+		if c.PageId == "" {
+			return nil, configErrorf("page or statuspage is required")
+		}
+		return []StatuspagePage{{StatuspagePageId: c.PageId}}, nil
+	}
+
+	config := StatuspageConfig{}
+	if err := loadFromFile(c.StatuspageFilename, &config); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	pages := config.Pages()
+	if c.PageId == "" {
+		return pages, nil
+	}
+	for _, p := range pages {
+		if p.StatuspagePageId == c.PageId || p.Name == c.PageId {
+			return []StatuspagePage{p}, nil
+		}
+	}
+	return []StatuspagePage{{StatuspagePageId: c.PageId}}, nil
+}
+
+// return component groups and components which don't belong to any group
+func topLevelComponents(components []StatuspageComponnet) []StatuspageComponnet {
+	result := make([]StatuspageComponnet, 0)
+	for _, c := range components {
+		if c.GroupId == "" {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// return components in the group
+func componentsInGroup(components []StatuspageComponnet, groupId string) []StatuspageComponnet {
+	result := make([]StatuspageComponnet, 0)
+	for _, c := range components {
+		if c.GroupId == groupId {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func printComponentsTable(w io.Writer, page StatuspagePage, components []StatuspageComponnet) {
+	fmt.Fprintf(w, "page: %s\n", page.label())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tNAME\tID\tSTATUS")
+	for _, c := range topLevelComponents(components) {
+		if !c.Group {
+			fmt.Fprintf(tw, "-\t%s\t%s\t%s\n", c.Name, c.Id, c.Status)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t-\t%s\t-\n", c.Name, c.Id)
+		for _, child := range componentsInGroup(components, c.Id) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name, child.Name, child.Id, child.Status)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// print `statuspageServices` stanza of statuspage.yaml.
+// A service is created for each component group and each component which doesn't belong to any group.
+// Each page is printed as a yaml document starting with `---`, so that the output of multiple pages is valid yaml.
+func printComponentsYaml(w io.Writer, page StatuspagePage, components []StatuspageComponnet) {
+	fmt.Fprintln(w, "---")
+	fmt.Fprintf(w, "statuspagePageId: %s\n", page.StatuspagePageId)
+	fmt.Fprintln(w, "statuspageServices:")
+	for _, c := range topLevelComponents(components) {
+		children := []StatuspageComponnet{c}
+		if c.Group {
+			children = componentsInGroup(components, c.Id)
+			if len(children) == 0 {
+				continue
+			}
+		}
+
+		fmt.Fprintf(w, "  - service: %q\n", c.Name)
+		fmt.Fprintln(w, "    componentIds:")
+		for _, child := range children {
+			fmt.Fprintf(w, "      - %s  # %s\n", child.Id, child.Name)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// print componentIds and componentNames of the page which are not found in components,
+// and return the count of them.
+func printStaleComponents(w io.Writer, page StatuspagePage, components []StatuspageComponnet) int {
+	exists := map[string]bool{}
+	for _, c := range components {
+		exists[c.Id] = true
+	}
+
+	count := 0
+	for _, s := range page.StatuspageServices {
+		for _, id := range s.ComponentIds {
+			if !exists[id] {
+				fmt.Fprintf(w, "stale: [%s] componentId %s no longer exists in page %s\n", s.Service, id, page.label())
+				count++
+			}
+		}
+		for _, name := range s.ComponentNames {
+			if _, err := resolveComponentName(name, components); err != nil {
+				fmt.Fprintf(w, "stale: [%s] %s in page %s\n", s.Service, err, page.label())
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,71 @@
+package maintenance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestPrintStaleComponents(t *testing.T) {
+	components := []StatuspageComponnet{
+		{Id: "g1", Name: "API", Group: true},
+		{Id: "c1", Name: "Search", GroupId: "g1"},
+		{Id: "c2", Name: "Login"},
+	}
+
+	page := StatuspagePage{
+		StatuspagePageId: "page1",
+		StatuspageServices: []StatuspageService{
+			{Service: "ServiceA", ComponentIds: []string{"c1", "removed"}},
+			{Service: "ServiceB", ComponentNames: []string{"API", "Unknown"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	count := printStaleComponents(&buf, page, components)
+	if count != 2 {
+		t.Errorf("exp:2, actual:%v\n%s", count, buf.String())
+	}
+	if !strings.Contains(buf.String(), "removed") || !strings.Contains(buf.String(), "Unknown") {
+		t.Errorf("stale components are not reported:\n%s", buf.String())
+	}
+}
+
+func TestPrintComponentsYaml(t *testing.T) {
+	components := []StatuspageComponnet{
+		{Id: "g1", Name: "API", Group: true},
+		{Id: "c1", Name: "Search", GroupId: "g1"},
+		{Id: "c2", Name: "Upload", GroupId: "g1"},
+		{Id: "c3", Name: "Login"},
+		{Id: "g2", Name: "Empty", Group: true},
+	}
+
+	var buf bytes.Buffer
+	printComponentsYaml(&buf, StatuspagePage{StatuspagePageId: "page1"}, components)
+	printComponentsYaml(&buf, StatuspagePage{StatuspagePageId: "page2"}, components[3:4])
+
+	// each page is a yaml document
+	decoder := yaml.NewDecoder(&buf)
+	config := StatuspageConfig{}
+	if err := decoder.Decode(&config); err != nil {
+		t.Fatalf("invalid yaml: %v", err)
+	}
+	config2 := StatuspageConfig{}
+	if err := decoder.Decode(&config2); err != nil {
+		t.Fatalf("invalid yaml: %v", err)
+	}
+	if config2.StatuspagePageId != "page2" || len(config2.StatuspageServices) != 1 {
+		t.Errorf("unexpected config: %+v", config2)
+	}
+	if config.StatuspagePageId != "page1" || len(config.StatuspageServices) != 2 {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+	if ids := config.StatuspageServices[0].ComponentIds; len(ids) != 2 || ids[0] != "c1" || ids[1] != "c2" {
+		t.Errorf("unexpected componentIds of API: %v", ids)
+	}
+	if ids := config.StatuspageServices[1].ComponentIds; len(ids) != 1 || ids[0] != "c3" {
+		t.Errorf("unexpected componentIds of Login: %v", ids)
+	}
+}
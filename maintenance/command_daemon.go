@@ -0,0 +1,137 @@
+package maintenance
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// interval to check modification of configuration files
+const daemonPollInterval = 10 * time.Second
+
+// Command which keeps reconciling recurring maintenances of the next days
+type DaemonCommand struct {
+	Recurring RecurringCommand // options of reconcile. FromDate and ToDate are set on each cycle
+	Day       int              // days of the rolling horizon, starting from today
+	Interval  time.Duration    // interval between cycles
+
+	MetricsAddr string // address to serve /metrics. metrics are not served if it's empty
+
+	reconcile func(c *RecurringCommand) error // for test. RecurringCommand.Run is used if nil
+}
+
+// execute daemon command until SIGINT or SIGTERM is received
+func (c *DaemonCommand) Run() error {
+	if c.Day <= 0 {
+		return configErrorf("day must be positive: %d", c.Day)
+	}
+	if c.Interval <= 0 {
+		return configErrorf("interval must be positive: %s", c.Interval)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if c.MetricsAddr != "" {
+		go c.serveMetrics()
+	}
+
+	ticker := time.NewTicker(daemonPollInterval)
+	defer ticker.Stop()
+
+	logger.Info("daemon started", "day", c.Day, "interval", c.Interval)
+	c.loop(signals, ticker.C, time.Now())
+	return nil
+}
+
+func (c *DaemonCommand) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.handler())
+	logger.Info("serving metrics", "addr", c.MetricsAddr)
+	if err := newHTTPServer(c.MetricsAddr, mux).ListenAndServe(); err != nil {
+		logger.Error("failed to serve metrics", "err", err)
+	}
+}
+
+// run a cycle at first, and then on every interval or when configuration files are changed.
+// return when a signal is received from stop.
+func (c *DaemonCommand) loop(stop <-chan os.Signal, poll <-chan time.Time, now time.Time) {
+	modTimes := c.modTimes()
+	c.runCycle(now)
+	last := now
+
+	for {
+		select {
+		case s := <-stop:
+			logger.Info("daemon stopped", "signal", s)
+			return
+		case now := <-poll:
+			current := c.modTimes()
+			changed := !isSameTimes(modTimes, current)
+			if changed {
+				logger.Info("configuration files are changed")
+				modTimes = current
+			}
+			if changed || now.Sub(last) >= c.Interval {
+				c.runCycle(now)
+				last = now
+			}
+		}
+	}
+}
+
+// reconcile maintenances from the date of now.
+// Errors are logged, and the daemon keeps running so that they can be fixed by the next cycle.
+func (c *DaemonCommand) runCycle(now time.Time) {
+	command := c.Recurring
+	command.FromDate = dateIn(now)
+	command.ToDate = command.FromDate.AddDate(0, 0, c.Day-1)
+
+	reconcile := c.reconcile
+	if reconcile == nil {
+		reconcile = (*RecurringCommand).Run
+	}
+
+	from := command.FromDate.Format(dateLayout)
+	to := command.ToDate.Format(dateLayout)
+	if err := reconcile(&command); err != nil {
+		logger.Error("cycle failed", "from", from, "to", to, "operations", len(command.report.Results), "failed", command.report.failedCount(), "err", err)
+		return
+	}
+	logger.Info("cycle succeeded", "from", from, "to", to, "operations", len(command.report.Results), "failed", command.report.failedCount())
+}
+
+// return modification times of schedule and statuspage files.
+// zero time is returned for files which can't be read.
+func (c *DaemonCommand) modTimes() []time.Time {
+	times := make([]time.Time, 0, 2)
+	for _, name := range []string{c.Recurring.ScheduleFilename, c.Recurring.StatuspageFilename} {
+		var t time.Time
+		if info, err := os.Stat(name); err == nil {
+			t = info.ModTime()
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+func isSameTimes(times1 []time.Time, times2 []time.Time) bool {
+	if len(times1) != len(times2) {
+		return false
+	}
+	for i := range times1 {
+		if !times1[i].Equal(times2[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// return the start of the date of t
+func dateIn(t time.Time) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
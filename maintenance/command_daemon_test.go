@@ -0,0 +1,71 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDaemonLoop(t *testing.T) {
+	dir := t.TempDir()
+	scheduleFilename := filepath.Join(dir, "schedule.yaml")
+	if err := os.WriteFile(scheduleFilename, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cycles := make([]RecurringCommand, 0)
+	command := DaemonCommand{
+		Recurring: RecurringCommand{ScheduleFilename: scheduleFilename},
+		Day:       30,
+		Interval:  time.Hour,
+		reconcile: func(c *RecurringCommand) error {
+			cycles = append(cycles, *c)
+			return nil
+		},
+	}
+
+	stop := make(chan os.Signal)
+	poll := make(chan time.Time)
+	done := make(chan bool)
+	start := timeOf(2020, 1, 1, 10, 0)
+	go func() {
+		command.loop(stop, poll, start)
+		done <- true
+	}()
+
+	// not yet elapsed interval
+	poll <- start.Add(10 * time.Minute)
+	// elapsed interval
+	poll <- start.Add(time.Hour)
+	// the loop has finished the previous poll when it receives the next one
+	poll <- start.Add(61 * time.Minute)
+	// schedule file is changed
+	modified := time.Now().Add(time.Minute)
+	if err := os.Chtimes(scheduleFilename, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	poll <- start.Add(70 * time.Minute)
+	// next day
+	poll <- start.Add(25 * time.Hour)
+	stop <- os.Interrupt
+	<-done
+
+	expected := []time.Time{
+		dateOf(2020, 1, 1),
+		dateOf(2020, 1, 1),
+		dateOf(2020, 1, 1),
+		dateOf(2020, 1, 2),
+	}
+	if len(cycles) != len(expected) {
+		t.Fatalf("exp:%v cycles, actual:%v", len(expected), len(cycles))
+	}
+	for idx, exp := range expected {
+		if !cycles[idx].FromDate.Equal(exp) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, exp, cycles[idx].FromDate)
+		}
+		if expTo := exp.AddDate(0, 0, 29); !cycles[idx].ToDate.Equal(expTo) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, expTo, cycles[idx].ToDate)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package maintenance
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	DriftKind_Missing   = "missing"   // scheduled but not registered
+	DriftKind_Extra     = "extra"     // registered but not scheduled
+	DriftKind_Changed   = "changed"   // registered with different time, title, body or components
+	DriftKind_Duplicate = "duplicate" // registered twice for the same schedule
+)
+
+// Difference between a maintenance registered in Statuspage and the schedule
+type Drift struct {
+	Page       string
+	Kind       string
+	Service    string
+	IncidentId string
+	Start      time.Time
+	End        time.Time
+	Detail     string
+}
+
+// Command which reports maintenances changed in the console
+type DriftCommand struct {
+	Recurring RecurringCommand // options to create schedule
+	Fix       bool             // register maintenances by the schedule when drifts are found
+}
+
+// execute drift command.
+// DriftError is returned if drifts are found and they are not fixed.
+func (c *DriftCommand) Run() error {
+	if c.Recurring.StateFilename != "" {
+		// the state is read to find maintenances changed in the console, and is not written
+		state, err := loadState(c.Recurring.StateFilename)
+		if err != nil {
+			return err
+		}
+		c.Recurring.state = state
+	}
+
+	pages, schedules, err := c.Recurring.load()
+	if err != nil {
+		return err
+	}
+
+	drifts := make([]Drift, 0)
+	for i, page := range pages {
+		repository := c.Recurring.getStatuspageRepository(page)
+		incidents, err := c.Recurring.listIncidents(repository, page)
+		if err != nil {
+			return err
+		}
+		incidents, duplicates := c.Recurring.findDuplicateIncidents(incidents)
+		for _, d := range duplicates {
+			drifts = append(drifts, Drift{
+				Page:       page.label(),
+				Kind:       DriftKind_Duplicate,
+				Service:    page.serviceOf(d),
+				IncidentId: d.Id,
+				Start:      d.ScheduledFor,
+				End:        d.ScheduledUntil,
+				Detail:     d.Name,
+			})
+		}
+		drifts = append(drifts, c.Recurring.detectDrifts(page, incidents, c.adjustSchedules(incidents, schedules[i], page))...)
+	}
+
+	printDrifts(os.Stdout, drifts)
+	if len(drifts) == 0 {
+		return nil
+	}
+	if c.Fix {
+		logger.Info("fix drifts", "count", len(drifts))
+		return c.Recurring.Run()
+	}
+	return &DriftError{Count: len(drifts)}
+}
+
+// return schedules adjusted by conflict policy.
+// Conflicts are not counted in metrics and not warned, because nothing is changed.
+func (c *DriftCommand) adjustSchedules(incidents []StatuspageIncident, schedules []ScheduledTerm, page StatuspagePage) []ScheduledTerm {
+	detector := c.Recurring
+	detector.isDryRun = true
+	detector.isDetectingDrifts = true
+	adjusted, _ := detector.adjustIncients(incidents, schedules, page)
+	return adjusted
+}
+
+// return differences between incidents of the page and schedules
+func (c *RecurringCommand) detectDrifts(page StatuspagePage, incidents []StatuspageIncident, schedules []ScheduledTerm) []Drift {
+	drifts := make([]Drift, 0)
+	matched := map[string]bool{}
+	for _, s := range schedules {
+		incident, diff := c.findSameIncident(incidents, page, s)
+		if incident == nil {
+			if c.isToBeRegistered(s) {
+				drifts = append(drifts, Drift{
+					Page:    page.label(),
+					Kind:    DriftKind_Missing,
+					Service: s.Service,
+					Start:   s.Start,
+					End:     s.End,
+					Detail:  s.Title,
+				})
+			}
+			continue
+		}
+
+		matched[incident.Id] = true
+		if !diff.IsEmpty() {
+			drifts = append(drifts, Drift{
+				Page:       page.label(),
+				Kind:       DriftKind_Changed,
+				Service:    s.Service,
+				IncidentId: incident.Id,
+				Start:      incident.ScheduledFor,
+				End:        incident.ScheduledUntil,
+				Detail:     diff.String(),
+			})
+		}
+	}
+
+	pruneFrom, pruneTo := c.pruneRange()
+	for _, i := range incidents {
+		if matched[i.Id] || !c.isOwnRecurringSchedule(i) || !i.isScheduled() || !isInDateRange(i.ScheduledFor, pruneFrom, pruneTo) {
+			continue
+		}
+		drifts = append(drifts, Drift{
+			Page:       page.label(),
+			Kind:       DriftKind_Extra,
+			Service:    page.serviceOf(i),
+			IncidentId: i.Id,
+			Start:      i.ScheduledFor,
+			End:        i.ScheduledUntil,
+			Detail:     i.Name,
+		})
+	}
+	return drifts
+}
+
+func printDrifts(w io.Writer, drifts []Drift) {
+	if len(drifts) == 0 {
+		fmt.Fprintln(w, "no drift")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PAGE\tKIND\tSERVICE\tINCIDENT\tSTART\tEND\tDETAIL")
+	for _, d := range drifts {
+		incidentId := d.IncidentId
+		if incidentId == "" {
+			incidentId = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Page,
+			d.Kind,
+			d.Service,
+			incidentId,
+			formatReportTime(d.Start),
+			formatReportTime(d.End),
+			d.Detail,
+		)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,139 @@
+package maintenance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectDrifts(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+		},
+	}
+	tomorrow := dateIn(time.Now()).AddDate(0, 0, 1)
+	command := RecurringCommand{
+		FromDate: tomorrow,
+		ToDate:   tomorrow.AddDate(0, 0, 2),
+	}
+
+	scheduleOf := func(key string, day int) ScheduledTerm {
+		start := tomorrow.AddDate(0, 0, day).Add(time.Hour)
+		return ScheduledTerm{Key: key, Service: "service1", Title: "title", Start: start, End: start.Add(time.Hour)}
+	}
+
+	schedules := []ScheduledTerm{
+		scheduleOf("same", 0),
+		scheduleOf("changed", 1),
+		scheduleOf("missing", 2),
+	}
+	incidents := []StatuspageIncident{
+		{
+			Id:             "1",
+			Name:           "title",
+			Components:     []StatuspageComponnet{{Id: "c1"}},
+			Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "same"}},
+			ScheduledFor:   schedules[0].Start,
+			ScheduledUntil: schedules[0].End,
+		},
+		{
+			Id:             "2",
+			Name:           "edited in console",
+			Components:     []StatuspageComponnet{{Id: "c1"}},
+			Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "changed"}},
+			ScheduledFor:   schedules[1].Start,
+			ScheduledUntil: schedules[1].End,
+		},
+		{
+			Id:             "3",
+			Name:           "title",
+			Components:     []StatuspageComponnet{{Id: "c1"}},
+			Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "removed"}},
+			ScheduledFor:   schedules[2].Start,
+			ScheduledUntil: schedules[2].End,
+		},
+	}
+
+	drifts := command.detectDrifts(page, incidents, schedules)
+
+	expected := []struct {
+		kind       string // expected
+		incidentId string // expected
+	}{
+		{DriftKind_Changed, "2"},
+		{DriftKind_Missing, ""},
+		{DriftKind_Extra, "3"},
+	}
+	if len(drifts) != len(expected) {
+		t.Fatalf("exp:%v drifts, actual:%v", len(expected), drifts)
+	}
+	for idx, exp := range expected {
+		if drifts[idx].Kind != exp.kind || drifts[idx].IncidentId != exp.incidentId {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, exp, drifts[idx])
+		}
+	}
+	if !strings.Contains(drifts[0].Detail, `"edited in console" -> "title"`) {
+		t.Errorf("changes of title should be shown: %s", drifts[0].Detail)
+	}
+
+	var buf bytes.Buffer
+	printDrifts(&buf, drifts)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 {
+		t.Errorf("header and 3 drifts should be printed: %s", buf.String())
+	}
+
+	if code := ExitCode(&DriftError{Count: len(drifts)}); code != ExitCodeDrift {
+		t.Errorf("exp:%v, actual:%v", ExitCodeDrift, code)
+	}
+
+	// body removed in console is detected, even though bodyHash in metadata is not changed
+	schedule := scheduleOf("same", 0)
+	schedule.Body = "body"
+	removed := StatuspageIncident{
+		Id:              "4",
+		Name:            "title",
+		Components:      []StatuspageComponnet{{Id: "c1"}},
+		Metadata:        map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "same", key_bodyHash: bodyHashOf("body")}},
+		IncidentUpdates: []StatuspageIncidentUpdateEntry{{Body: "in progress", CreatedAt: schedule.Start}},
+		ScheduledFor:    schedule.Start,
+		ScheduledUntil:  schedule.End,
+	}
+	drifts = command.detectDrifts(page, []StatuspageIncident{removed}, []ScheduledTerm{schedule})
+	if len(drifts) != 1 || drifts[0].Kind != DriftKind_Changed || drifts[0].IncidentId != "4" {
+		t.Errorf("removed body should be detected: %v", drifts)
+	}
+}
+
+func TestDriftAdjustSchedules(t *testing.T) {
+	m := newTestMetrics(t)
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+		},
+	}
+	schedules := []ScheduledTerm{
+		{Key: "key", Service: "service1", Start: timeOf(2020, 1, 1, 1, 0), End: timeOf(2020, 1, 1, 2, 0)},
+	}
+	manual := StatuspageIncident{
+		Id:             "1",
+		Components:     []StatuspageComponnet{{Id: "c1"}},
+		ScheduledFor:   timeOf(2020, 1, 1, 1, 30),
+		ScheduledUntil: timeOf(2020, 1, 1, 3, 0),
+	}
+
+	command := DriftCommand{Recurring: RecurringCommand{ConflictPolicy: ConflictPolicy_Split, FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 1), now: nowOf(dateOf(2019, 12, 31))}}
+	actual := command.adjustSchedules([]StatuspageIncident{manual}, schedules, page)
+	if len(actual) != 1 || !actual[0].End.Equal(manual.ScheduledFor) {
+		t.Errorf("schedule should be split: %v", actual)
+	}
+
+	// conflicts are not counted by drift detection
+	if count := m.incidents.get("", "service1", Operation_Conflict); count != 0 {
+		t.Errorf("exp:%v, actual:%v", 0, count)
+	}
+	if command.Recurring.isDryRun || command.Recurring.isDetectingDrifts {
+		t.Errorf("options of the command should not be changed: %+v", command.Recurring)
+	}
+}
@@ -0,0 +1,200 @@
+package maintenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Command which registers a maintenance of a service once
+type OnceCommand struct {
+	Recurring RecurringCommand // options of statuspage file, owner, dryRun, audit log, state and lock
+	Service   string
+	Title     string // title and body are rendered as templates like recurring maintenances
+	Body      string
+	Start     time.Time
+	End       time.Time
+}
+
+// execute once command.
+// The maintenance is registered to all pages which have the service, unless the same maintenance is registered.
+func (c *OnceCommand) Run() error {
+	if err := c.validate(time.Now()); err != nil {
+		return err
+	}
+	return c.Recurring.execute(c.run)
+}
+
+func (c *OnceCommand) run() error {
+	pages, err := c.pages()
+	if err != nil {
+		return err
+	}
+
+	c.Recurring.report = Report{}
+	registered := make([]string, 0)
+	for _, page := range pages {
+		registered = append(registered, page.label())
+		if err := c.register(page); err != nil {
+			c.Recurring.report.PrintSummary(os.Stdout, registered)
+			return err
+		}
+	}
+	c.Recurring.report.PrintSummary(os.Stdout, registered)
+	return nil
+}
+
+func (c *OnceCommand) validate(now time.Time) error {
+	if c.Service == "" {
+		return configErrorf("service is required")
+	}
+	if c.Title == "" {
+		return configErrorf("title is required")
+	}
+	if c.Start.IsZero() || c.End.IsZero() {
+		return configErrorf("start and end (or duration) are required")
+	}
+	if !c.End.After(c.Start) {
+		return configErrorf("end must be after start: %s - %s", c.Start, c.End)
+	}
+	if !c.End.After(now) {
+		return configErrorf("maintenance has already ended: %s", c.End)
+	}
+	if err := validateTemplate("title", c.Title); err != nil {
+		return configErrorf("invalid title: %w", err)
+	}
+	if err := validateTemplate("body", c.Body); err != nil {
+		return configErrorf("invalid body: %w", err)
+	}
+	return nil
+}
+
+// load statuspage file, and return pages which have the service
+func (c *OnceCommand) pages() ([]StatuspagePage, error) {
+	config := StatuspageConfig{}
+	if err := loadFromFile(c.Recurring.StatuspageFilename, &config); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if !config.hasService(c.Service) {
+		return nil, configErrorf("service is not found in %s: %s", c.Recurring.StatuspageFilename, c.Service)
+	}
+	if c.Recurring.Owner == "" {
+		c.Recurring.Owner = config.Owner
+	}
+
+	pages := make([]StatuspagePage, 0)
+	for _, page := range config.Pages() {
+		if page.findComponentByServiceName(c.Service) == nil {
+			continue
+		}
+		if err := c.Recurring.loadComponents(&page); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// register the maintenance to the page
+func (c *OnceCommand) register(page StatuspagePage) error {
+	repository := c.Recurring.getStatuspageRepository(page)
+	schedule, err := c.scheduledTerm(page)
+	if err != nil {
+		return err
+	}
+
+	incidents, err := repository.FindAllScheduledIncidents(1, 200)
+	if err != nil {
+		return c.Recurring.record(OperationResult{
+			Page:      page.label(),
+			Operation: Operation_List,
+			Err:       fmt.Errorf("FindAllScheduledIncidents err: %w", err),
+		})
+	}
+	for _, i := range incidents {
+		if i.isOneoffSchedule() && i.scheduleKey() == schedule.Key && i.isOwnedBy(c.Recurring.Owner) {
+			logger.Info("maintenance is already registered", "page", page.label(), "service", c.Service, "incidentId", i.Id)
+			return nil
+		}
+	}
+
+	c.Recurring.logOperation("add maintenance", "service", c.Service, "name", schedule.Title, "start", schedule.Start, "end", schedule.End)
+	incident, err := repository.Add(CreateMaintenanceStatuspageData(
+		schedule.Title,
+		schedule.Body,
+		schedule.componentIdsIn(page),
+		schedule.Start,
+		schedule.End,
+		page.MaintenanceDefaults,
+		ScheduleType_Oneoff,
+		schedule.Key,
+		c.Recurring.Owner,
+	))
+	if err == nil {
+		c.Recurring.state.put(StateIncident{
+			PageId:         page.StatuspagePageId,
+			IncidentId:     incident.Id,
+			ScheduleKey:    schedule.Key,
+			Name:           schedule.Title,
+			ScheduledFor:   schedule.Start,
+			ScheduledUntil: schedule.End,
+		})
+	}
+	return c.Recurring.record(OperationResult{
+		Page:       page.label(),
+		Service:    c.Service,
+		Operation:  Operation_Add,
+		Name:       schedule.Title,
+		IncidentId: incident.Id,
+		Start:      schedule.Start,
+		End:        schedule.End,
+		Err:        err,
+	})
+}
+
+// return the maintenance rendered for the page
+func (c *OnceCommand) scheduledTerm(page StatuspagePage) (ScheduledTerm, error) {
+	service := page.findComponentByServiceName(c.Service)
+	data := TemplateData{
+		Service:     c.Service,
+		Description: service.Description,
+		Start:       c.Start,
+		End:         c.End,
+		StartUTC:    c.Start.UTC(),
+		EndUTC:      c.End.UTC(),
+		Duration:    c.End.Sub(c.Start),
+		Ordinal:     1,
+		Count:       1,
+		Components:  page.componentNamesOf(service.ComponentIds),
+	}
+	title, err := LocalizedText{noLocale: c.Title}.render("title", data, page.Locales, page.LocaleLayout.TitleSeparator)
+	if err != nil {
+		return ScheduledTerm{}, configErrorf("[%s] %w", c.Service, err)
+	}
+	body, err := LocalizedText{noLocale: c.Body}.render("body", data, page.Locales, page.LocaleLayout.BodySeparator)
+	if err != nil {
+		return ScheduledTerm{}, configErrorf("[%s] %w", c.Service, err)
+	}
+
+	return ScheduledTerm{
+		Key:          createOneoffKey(c.Service, c.Start, c.End),
+		Service:      c.Service,
+		Title:        title,
+		Body:         body,
+		Start:        c.Start,
+		End:          c.End,
+		ComponentIds: service.ComponentIds,
+	}, nil
+}
+
+// return stable key of the maintenance, so that the same maintenance is not registered twice
+func createOneoffKey(service string, start time.Time, end time.Time) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{ScheduleType_Oneoff, service, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)}, "\x00")))
+	return hex.EncodeToString(hash[:8])
+}
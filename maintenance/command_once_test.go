@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOnceValidate(t *testing.T) {
+	now := timeOf(2020, 1, 1, 10, 0)
+	start := now.Add(time.Hour)
+
+	patterns := []struct {
+		command OnceCommand // input
+		isValid bool        // expected
+	}{
+		{OnceCommand{Service: "ServiceA", Title: "title", Start: start, End: start.Add(time.Hour)}, true},
+		// started, but not ended
+		{OnceCommand{Service: "ServiceA", Title: "title", Start: now.Add(-time.Hour), End: now.Add(time.Minute)}, true},
+		{OnceCommand{Title: "title", Start: start, End: start.Add(time.Hour)}, false},
+		{OnceCommand{Service: "ServiceA", Start: start, End: start.Add(time.Hour)}, false},
+		{OnceCommand{Service: "ServiceA", Title: "title", Start: start}, false},
+		{OnceCommand{Service: "ServiceA", Title: "title", Start: start, End: start}, false},
+		{OnceCommand{Service: "ServiceA", Title: "title", Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}, false},
+		{OnceCommand{Service: "ServiceA", Title: "{{.Unknown", Start: start, End: start.Add(time.Hour)}, false},
+	}
+
+	for idx, row := range patterns {
+		err := row.command.validate(now)
+		if (err == nil) != row.isValid {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.isValid, err)
+		}
+	}
+}
+
+func TestOnceRegister(t *testing.T) {
+	statuspageFilename := filepath.Join(t.TempDir(), "statuspage.yaml")
+	statuspage := `
+owner: team1
+statuspagePageId: page1
+statuspageServices:
+  - service: ServiceA
+    componentIds: ["c1"]
+statuspagePages:
+  - statuspagePageId: page2
+    statuspageServices:
+      - service: ServiceB
+        componentIds: ["c2"]
+`
+	if err := os.WriteFile(statuspageFilename, []byte(statuspage), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(time.Hour).Truncate(time.Minute)
+	repositories := map[string]*fakeRepository{"page1": {}, "page2": {}}
+	stateFilename := filepath.Join(t.TempDir(), "state.json")
+	locker := &fakeLocker{}
+	command := OnceCommand{
+		Recurring: RecurringCommand{
+			StatuspageFilename: statuspageFilename,
+			StateFilename:      stateFilename,
+			Locker:             locker,
+			repositoryOf: func(page StatuspagePage) StatuspageRepository {
+				return repositories[page.StatuspagePageId]
+			},
+		},
+		Service: "ServiceA",
+		Title:   "Emergency maintenance of {{.Service}}",
+		Start:   start,
+		End:     start.Add(30 * time.Minute),
+	}
+	if err := command.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if locker.acquired != 1 || locker.locked {
+		t.Errorf("lock should be acquired and released: %+v", locker)
+	}
+	state, err := loadState(stateFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := state.find("page1", "added1"); s == nil || s.ScheduleKey == "" || !s.ScheduledFor.Equal(start) {
+		t.Errorf("registered maintenance should be recorded in state: %v", state.Incidents)
+	}
+
+	if len(repositories["page2"].added) != 0 {
+		t.Errorf("maintenance should not be registered to page without the service: %v", repositories["page2"].added)
+	}
+	added := repositories["page1"].added
+	if len(added) != 1 {
+		t.Fatalf("exp:1, actual:%v", len(added))
+	}
+	request := added[0].Incident
+	metadata := request.Metadata[key_toolNamespace].(map[string]interface{})
+	if request.Name != "Emergency maintenance of ServiceA" || metadata[key_scheduleType] != ScheduleType_Oneoff || metadata[key_owner] != "team1" {
+		t.Errorf("unexpected request: %v %v", request.Name, metadata)
+	}
+
+	// the same maintenance is not registered again, and is not deleted by recurring command
+	incident := StatuspageIncident{
+		Id:             "1",
+		Components:     []StatuspageComponnet{{Id: "c1"}},
+		Metadata:       map[string]map[string]interface{}{key_toolNamespace: metadata},
+		ScheduledFor:   start,
+		ScheduledUntil: start.Add(30 * time.Minute),
+	}
+	repositories["page1"] = &fakeRepository{incidents: []StatuspageIncident{incident}}
+	if err := command.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories["page1"].added) != 0 {
+		t.Errorf("registered maintenance should be skipped: %v", repositories["page1"].added)
+	}
+
+	page := StatuspagePage{StatuspageServices: []StatuspageService{{Service: "ServiceA", ComponentIds: []string{"c1"}}}}
+	recurring := RecurringCommand{Owner: "team1", FromDate: dateIn(start), ToDate: dateIn(start)}
+	if err := recurring.deleteIncidents(repositories["page1"], []StatuspageIncident{incident}, page, []ScheduledTerm{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories["page1"].deleted) != 0 {
+		t.Errorf("oneoff maintenance should not be deleted: %v", repositories["page1"].deleted)
+	}
+}
@@ -1,9 +1,14 @@
 package maintenance
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -13,22 +18,61 @@ type RecurringCommand struct {
 	FromDate         time.Time
 	ToDate           time.Time
 
+	// Maintenances which are not in schedule are deleted between PruneFromDate and PruneToDate (inclusive).
+	// FromDate and ToDate are used if they are zero.
+	NoPrune       bool
+	PruneFromDate time.Time
+	PruneToDate   time.Time
+
+	// Maintenances in progress are never deleted. They are updated when UpdateInProgress is true.
+	UpdateInProgress bool
+	// Schedules which started within GracePeriod are registered
+	GracePeriod time.Duration
+
 	isDryRun           bool
+	ContinueOnError    bool
 	StatuspageFilename string
 	AccessToken        string
+	Owner              string // overrides owner of statuspage.yaml
+	ConflictPolicy     string // overrides conflictPolicy of statuspage.yaml
+
+	// Changes made to Statuspage are appended to AuditLogFilename if it's specified
+	AuditLogFilename string
+	Operator         string // operator of changes written in audit log. see operatorOf
+
+	// Maintenances created by this command are recorded in StateFilename if it's specified
+	StateFilename string
+
+	// Lock is held from reading incidents to applying changes, so that concurrent runs don't register the same maintenances.
+	// Locker is used if it's set, or FileLocker of LockFilename if it's specified. Lock is not used in dry run.
+	LockFilename string
+	Locker       Locker
+
+	report   Report
+	auditLog *AuditLog
+	state    *State
+
+	isDetectingDrifts bool // only schedules are adjusted to detect drifts. conflicts are logged as debug
+
+	repositoryOf func(page StatuspagePage) StatuspageRepository // for test. see getStatuspageRepository
+	now          func() time.Time                               // for test. time.Now is used if it's nil
 }
 
 type RecurringMaintenance struct {
-	Service            string               `yaml:"service"`
-	Title              string               `yaml:"title"`
-	Body               string               `yaml:"body"`
-	RecurringSchedules []RecurringSchedules `yaml:"recurring"`
+	Service            string               `yaml:"service" json:"service"`
+	Title              LocalizedText        `yaml:"title" json:"title"`
+	Body               LocalizedText        `yaml:"body" json:"body,omitempty"`
+	BodyFile           LocalizedText        `yaml:"bodyFile" json:"bodyFile,omitempty"` // Markdown file of body. Relative path is resolved from the directory of schedule file
+	RecurringSchedules []RecurringSchedules `yaml:"recurring" json:"recurring"`
+
+	// Options of the maintenance. Unspecified options are taken from maintenanceDefaults in statuspage.yaml.
+	Options MaintenanceOptions `yaml:",inline" json:"options"`
 }
 
 type RecurringSchedules struct {
-	Day   string `yaml:"day"`
-	Start string `yaml:"start"`
-	Time  string `yaml:"time"`
+	Day   string `yaml:"day" json:"day"`
+	Start string `yaml:"start" json:"start"`
+	Time  string `yaml:"time" json:"time"`
 }
 
 type Term struct {
@@ -37,64 +81,582 @@ type Term struct {
 }
 
 type ScheduledTerm struct {
-	Service string
-	Title   string
-	Body    string
-	Start   time.Time
-	End     time.Time
+	Key          string             `json:"key"` // stable key of the occurrence. see createScheduleKey
+	Service      string             `json:"service"`
+	Title        string             `json:"title"`
+	Body         string             `json:"body"`
+	Start        time.Time          `json:"start"`
+	End          time.Time          `json:"end"`
+	ComponentIds []string           `json:"componentIds"` // components of the maintenance. see componentIdsIn
+	Options      MaintenanceOptions `json:"options"`
 }
 
 const everyOrdinal = -1
 
+// Policies when a recurring maintenance is overlapped with a maintenance created by other way
+const (
+	ConflictPolicy_Skip           = "skip"            // don't register the recurring maintenance
+	ConflictPolicy_Split          = "split"           // register parts of the recurring maintenance which are not overlapped
+	ConflictPolicy_ExtendManual   = "extend-manual"   // extend the other maintenance to contain the recurring maintenance
+	ConflictPolicy_RegisterAnyway = "register-anyway" // register the recurring maintenance as it is
+)
+
+var conflictPolicies = []string{
+	ConflictPolicy_Skip,
+	ConflictPolicy_Split,
+	ConflictPolicy_ExtendManual,
+	ConflictPolicy_RegisterAnyway,
+}
+
+// Update of an incident which is planned by the command
+type incidentUpdate struct {
+	Incident StatuspageIncident
+	Request  StatuspageUpdateIncidentRequest
+}
+
 // execute recurring command
-func (c *RecurringCommand) Run() {
+func (c *RecurringCommand) Run() error {
+	err := c.execute(c.run)
+	if !c.isDryRun {
+		if err == nil {
+			metrics.runs.add(1, "success")
+			metrics.lastSuccess.set(float64(time.Now().Unix()))
+		} else {
+			metrics.runs.add(1, "failure")
+		}
+	}
+	return err
+}
+
+// execute fn holding the lock, with the audit log and the state of the command.
+// The state is saved after fn, also on failure.
+func (c *RecurringCommand) execute(fn func() error) error {
+	if locker := c.getLocker(); locker != nil && !c.isDryRun {
+		if err := locker.Lock(); err != nil {
+			return err
+		}
+		defer func() {
+			if err := locker.Unlock(); err != nil {
+				logger.Error("failed to release lock", "err", err)
+			}
+		}()
+	}
+
+	if c.AuditLogFilename != "" && !c.isDryRun {
+		auditLog, closeAuditLog, err := openAuditLog(c.AuditLogFilename, operatorOf(c.Operator), configCommitOf(c.StatuspageFilename))
+		if err != nil {
+			return err
+		}
+		defer closeAuditLog()
+		c.auditLog = auditLog
+		logger.Info("audit log", "file", c.AuditLogFilename, "runId", auditLog.RunId, "operator", auditLog.Operator)
+	}
+
+	if c.StateFilename != "" {
+		state, err := loadState(c.StateFilename)
+		if err != nil {
+			return err
+		}
+		c.state = state
+	}
+
+	err := fn()
+	if c.state != nil && !c.isDryRun {
+		// saved also on failure, so that created maintenances are not lost
+		if saveErr := c.state.save(c.StateFilename); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// return locker of the run. nil is returned if lock is not used.
+func (c *RecurringCommand) getLocker() Locker {
+	if c.Locker != nil {
+		return c.Locker
+	}
+	if c.LockFilename != "" {
+		c.Locker = &FileLocker{Filename: c.LockFilename}
+		return c.Locker
+	}
+	return nil
+}
+
+func (c *RecurringCommand) run() error {
+	pages, schedules, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	c.report = Report{}
+	reconciled := make([]string, 0)
+	for i, page := range pages {
+		logger.Info("reconcile page", "page", page.label(), "from", c.FromDate, "to", c.ToDate, "dryRun", c.isDryRun)
+
+		reconciled = append(reconciled, page.label())
+		err = c.reconcilePage(page, schedules[i])
+		if err != nil {
+			break
+		}
+	}
+
+	if c.ContinueOnError {
+		c.report.Print(os.Stdout, reconciled)
+		// errors which are not recorded as results of operations are returned first
+		if err != nil {
+			return err
+		}
+		return c.report.err()
+	}
+	c.report.PrintSummary(os.Stdout, reconciled)
+	return err
+}
 
+// load schedule and statuspage files, and return pages and schedule of each page
+func (c *RecurringCommand) load() ([]StatuspagePage, [][]ScheduledTerm, error) {
 	maintenances := make([]RecurringMaintenance, 1)
-	loadFromFile(c.ScheduleFilename, &maintenances)
+	if err := loadFromFile(c.ScheduleFilename, &maintenances); err != nil {
+		return nil, nil, err
+	}
 
 	statuspageConfig := StatuspageConfig{}
-	loadFromFile(c.StatuspageFilename, &statuspageConfig)
+	if err := loadFromFile(c.StatuspageFilename, &statuspageConfig); err != nil {
+		return nil, nil, err
+	}
 
-	scheduledTerms := c.CreateSchedule(maintenances)
+	if err := statuspageConfig.validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := validateMaintenances(maintenances, statuspageConfig, c.scheduleDir()); err != nil {
+		return nil, nil, err
+	}
 
-	repository := c.getStatuspageRepository(statuspageConfig.StatuspagePageId)
+	if c.Owner == "" {
+		c.Owner = statuspageConfig.Owner
+	}
+	if c.ConflictPolicy == "" {
+		c.ConflictPolicy = statuspageConfig.ConflictPolicy
+	}
+	if err := validateConflictPolicy(c.ConflictPolicy); err != nil {
+		return nil, nil, err
+	}
+
+	pages := statuspageConfig.Pages()
+	schedules := make([][]ScheduledTerm, len(pages))
+	for i := range pages {
+		if err := c.loadComponents(&pages[i]); err != nil {
+			return nil, nil, err
+		}
 
+		scheduledTerms, err := c.CreateSchedule(maintenances, pages[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		schedules[i] = pages[i].filterSchedules(scheduledTerms)
+	}
+	return pages, schedules, nil
+}
+
+// count maintenances in metrics. Nothing is counted in dry run.
+func (c *RecurringCommand) countIncident(page string, service string, operation string) {
+	if !c.isDryRun {
+		metrics.incidents.add(1, page, service, operation)
+	}
+}
+
+// return scheduled maintenances and maintenances in progress of the page, including those in the local state
+func (c *RecurringCommand) listIncidents(repository StatuspageRepository, page StatuspagePage) ([]StatuspageIncident, error) {
 	incidents, err := repository.FindAllScheduledIncidents(1, 200)
 	if err != nil {
-		log.Fatalf("[ERORR] FindAllScheduledIncidents err: %s", err.Error())
+		return nil, fmt.Errorf("FindAllScheduledIncidents err: %w", err)
 	}
 	if len(incidents) >= 200 {
-		log.Fatalf("[ERORR] too many incidents are registered: %d", len(incidents))
+		return nil, fmt.Errorf("too many incidents are registered: %d", len(incidents))
+	}
+
+	active, err := repository.FindAllActiveMaintenances(1, 100)
+	if err != nil {
+		return nil, fmt.Errorf("FindAllActiveMaintenances err: %w", err)
+	}
+	incidents = append(incidents, active...)
+
+	return c.syncState(repository, page, incidents)
+}
+
+// compare incidents of the page with the local state, and return incidents including those in the state.
+// Maintenances in the state which are not listed are taken by id, so that they are updated or deleted precisely.
+// Changes and deletions made in the console are logged.
+func (c *RecurringCommand) syncState(repository StatuspageRepository, page StatuspagePage, incidents []StatuspageIncident) ([]StatuspageIncident, error) {
+	if c.state == nil {
+		return incidents, nil
+	}
+	pageId := page.StatuspagePageId
+
+	listed := map[string]bool{}
+	for _, i := range incidents {
+		listed[i.Id] = true
+		if !c.isOwnRecurringSchedule(i) {
+			continue
+		}
+		if s := c.state.find(pageId, i.Id); s != nil && !s.isSame(i) {
+			logChangedIncident(page, *s, i)
+		}
+		c.state.put(stateIncidentOf(pageId, i))
 	}
-	scheduledTerms = c.adjustIncients(incidents, scheduledTerms, statuspageConfig)
 
-	c.deleteIncidents(repository, incidents, statuspageConfig, scheduledTerms)
-	c.registerIncidents(repository, incidents, statuspageConfig, scheduledTerms)
+	for _, s := range c.state.incidentsOf(pageId) {
+		if listed[s.IncidentId] {
+			continue
+		}
+		incident, err := repository.FindIncident(s.IncidentId)
+		if err != nil {
+			return nil, fmt.Errorf("FindIncident err: %w", err)
+		}
+		if incident == nil {
+			logger.Warn("maintenance was deleted in console",
+				"page", page.label(), "incidentId", s.IncidentId, "name", s.Name, "start", s.ScheduledFor, "end", s.ScheduledUntil)
+			c.state.remove(pageId, s.IncidentId)
+			continue
+		}
+		if !incident.isScheduled() && !incident.isInProgress() {
+			// completed maintenances are no longer managed
+			c.state.remove(pageId, s.IncidentId)
+			continue
+		}
+		if !s.isSame(*incident) {
+			logChangedIncident(page, s, *incident)
+		}
+		c.state.put(stateIncidentOf(pageId, *incident))
+		incidents = append(incidents, *incident)
+	}
+	return incidents, nil
+}
+
+func logChangedIncident(page StatuspagePage, s StateIncident, incident StatuspageIncident) {
+	logger.Warn("maintenance was changed in console",
+		"page", page.label(),
+		"incidentId", incident.Id,
+		"name", incident.Name,
+		"start", incident.ScheduledFor,
+		"end", incident.ScheduledUntil,
+		"stateName", s.Name,
+		"stateStart", s.ScheduledFor,
+		"stateEnd", s.ScheduledUntil)
+}
+
+// set count of future schedules in the horizon for each service of the page.
+// schedules must be those registered in Statuspage. Nothing is set in dry run.
+func (c *RecurringCommand) setScheduledWindows(page StatuspagePage, schedules []ScheduledTerm) {
+	if c.isDryRun {
+		return
+	}
+	counts := map[string]int{}
+	for _, s := range page.StatuspageServices {
+		counts[s.Service] = 0
+	}
+	now := c.currentTime()
+	for _, s := range schedules {
+		if s.Start.After(now) && isInDateRange(s.Start, c.FromDate, c.ToDate) {
+			counts[s.Service]++
+		}
+	}
+	for service, count := range counts {
+		metrics.scheduledWindows.set(float64(count), page.label(), service)
+	}
+}
+
+// take components of the page from Statuspage, and resolve componentNames of the page
+func (c *RecurringCommand) loadComponents(page *StatuspagePage) error {
+	components, err := findAllComponents(c.getStatuspageRepository(*page))
+	if err != nil {
+		return fmt.Errorf("FindAllComponents err: %w", err)
+	}
+	return page.resolveComponentNames(components)
+}
+
+// register and delete maintenances of a page
+func (c *RecurringCommand) reconcilePage(page StatuspagePage, scheduledTerms []ScheduledTerm) error {
+	repository := c.getStatuspageRepository(page)
+
+	incidents, err := c.listIncidents(repository, page)
+	if err != nil {
+		return c.record(OperationResult{
+			Page:      page.label(),
+			Operation: Operation_List,
+			Err:       err,
+		})
+	}
+
+	incidents, duplicates := c.findDuplicateIncidents(incidents)
+	notDeleted, err := c.deleteDuplicateIncidents(repository, page, duplicates)
+	if err != nil {
+		return err
+	}
+	// duplicates which are not deleted are kept after the others, so that the oldest ones are matched with schedules
+	incidents = append(incidents, notDeleted...)
+
+	scheduledTerms, updates := c.adjustIncients(incidents, scheduledTerms, page)
+	updates = append(updates, c.diffIncidents(incidents, page, scheduledTerms)...)
+
+	err = c.updateIncidents(repository, page, updates)
+	if err != nil {
+		return err
+	}
+	err = c.deleteIncidents(repository, incidents, page, scheduledTerms)
+	if err != nil {
+		return err
+	}
+	return c.registerIncidents(repository, incidents, page, scheduledTerms)
+}
+
+func validateConflictPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, p := range conflictPolicies {
+		if p == policy {
+			return nil
+		}
+	}
+	return configErrorf("unknown conflict policy: %s", policy)
+}
+
+// return ConfigError if maintenances have invalid values
+func validateMaintenances(maintenances []RecurringMaintenance, config StatuspageConfig, baseDir string) error {
+	for _, m := range maintenances {
+		if !config.hasService(m.Service) {
+			return configErrorf("unknown service is found: %s", m.Service)
+		}
+		if err := m.Options.validate(); err != nil {
+			return configErrorf("[%s] %w", m.Service, err)
+		}
+		if err := m.Title.validate("title"); err != nil {
+			return configErrorf("[%s] %w", m.Service, err)
+		}
+		body, err := m.loadBody(baseDir)
+		if err != nil {
+			return err
+		}
+		if err := body.validate("body"); err != nil {
+			return configErrorf("[%s] %w", m.Service, err)
+		}
+		for _, s := range m.RecurringSchedules {
+			if err := s.validate(); err != nil {
+				return configErrorf("[%s] %w", m.Service, err)
+			}
+		}
+	}
+	return nil
+}
+
+// return directory of schedule file, which is base directory of bodyFile
+func (c *RecurringCommand) scheduleDir() string {
+	return filepath.Dir(c.ScheduleFilename)
+}
+
+// return body of the maintenance. bodyFile is loaded and merged into body.
+func (m RecurringMaintenance) loadBody(baseDir string) (LocalizedText, error) {
+	if len(m.BodyFile) == 0 {
+		return m.Body, nil
+	}
+	if m.Body.isLocalized() != m.BodyFile.isLocalized() && len(m.Body) > 0 {
+		return nil, configErrorf("[%s] body and bodyFile should be both localized or not", m.Service)
+	}
+
+	body := LocalizedText{}
+	for locale, text := range m.Body {
+		body[locale] = text
+	}
+	for locale, fileName := range m.BodyFile {
+		if _, ok := body[locale]; ok {
+			return nil, configErrorf("[%s] both body and bodyFile are specified", m.Service)
+		}
+		if !filepath.IsAbs(fileName) {
+			fileName = filepath.Join(baseDir, fileName)
+		}
+		buf, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, configErrorf("[%s] failed to load bodyFile: %w", m.Service, err)
+		}
+		body[locale] = string(buf)
+	}
+	return body, nil
+}
+
+// record result of an operation.
+// error is returned to stop the command unless ContinueOnError is enabled.
+func (c *RecurringCommand) record(result OperationResult) error {
+	c.report.add(result)
+	if result.isSucceeded() {
+		c.countIncident(result.Page, result.Service, result.Operation)
+	} else if !c.isDryRun {
+		metrics.operationFailures.add(1, result.Page, result.Operation)
+	}
+	if result.Err != nil && !c.ContinueOnError {
+		return result.Err
+	}
+	return nil
+}
+
+// log an operation to Statuspage with the service. Operations in dry run are logged with [dryRun] prefix
+func (c *RecurringCommand) logOperation(msg string, fields ...interface{}) {
+	if c.isDryRun {
+		msg = "[dryRun] " + msg
+	}
+	logger.Info(msg, fields...)
 }
 
 //-------------------------------
 // Create schedule of maintenance
 //-------------------------------
 
-func (c *RecurringCommand) CreateSchedule(maintenances []RecurringMaintenance) []ScheduledTerm {
+// create schedule of maintenances.
+// title and body of maintenances are rendered as templates with information of services in the page,
+// and texts of locales are composed by the layout of the page.
+func (c *RecurringCommand) CreateSchedule(maintenances []RecurringMaintenance, page StatuspagePage) ([]ScheduledTerm, error) {
+
+	fromDate, toDate := c.scheduleRange()
 
 	scheduledTerms := make([]ScheduledTerm, 0)
 	for _, ps := range maintenances {
 		terms := make([]*Term, 0)
+		keys := map[*Term]string{}
 		for _, s := range ps.RecurringSchedules {
-			terms = c.margeTerms(terms, s.CreateTerms(c.FromDate, c.ToDate))
+			created := s.CreateTerms(fromDate, toDate)
+			for _, t := range created {
+				keys[t] = createScheduleKey(ps.Service, s, t)
+			}
+			terms = c.margeTerms(terms, created)
+		}
+		sort.SliceStable(terms, func(i, j int) bool {
+			return terms[i].Start.Before(terms[j].Start)
+		})
+
+		service := page.findComponentByServiceName(ps.Service)
+		if service == nil {
+			service = &StatuspageService{Service: ps.Service}
+		}
+
+		bodyText, err := ps.loadBody(c.scheduleDir())
+		if err != nil {
+			return nil, err
 		}
+
+		monthTerms := map[string][]*Term{}
 		for _, t := range terms {
+			month := t.Start.Format("2006-01")
+			if _, ok := monthTerms[month]; !ok {
+				monthTerms[month] = c.createMonthTerms(ps, t.Start)
+			}
+			ordinal, count := ordinalOfTerm(monthTerms[month], t)
+
+			data := TemplateData{
+				Service:     ps.Service,
+				Description: service.Description,
+				Start:       t.Start,
+				End:         t.End,
+				StartUTC:    t.Start.UTC(),
+				EndUTC:      t.End.UTC(),
+				Duration:    t.End.Sub(t.Start),
+				Ordinal:     ordinal,
+				Count:       count,
+				Components:  page.componentNamesOf(service.ComponentIds),
+			}
+			title, err := ps.Title.render("title", data, page.Locales, page.LocaleLayout.TitleSeparator)
+			if err != nil {
+				return nil, configErrorf("[%s] %w", ps.Service, err)
+			}
+			body, err := bodyText.render("body", data, page.Locales, page.LocaleLayout.BodySeparator)
+			if err != nil {
+				return nil, configErrorf("[%s] %w", ps.Service, err)
+			}
+
 			scheduledTerms = append(scheduledTerms, ScheduledTerm{
-				Service: ps.Service,
-				Start:   t.Start,
-				End:     t.End,
-				Title:   ps.Title,
-				Body:    ps.Body,
+				Key:          keys[t],
+				Service:      ps.Service,
+				Start:        t.Start,
+				End:          t.End,
+				Title:        title,
+				Body:         body,
+				ComponentIds: service.ComponentIds,
+				Options:      ps.Options,
 			})
 		}
 	}
-	return scheduledTerms
+	return scheduledTerms, nil
+}
+
+// return terms of the maintenance in the calendar month of t.
+// Ordinal and count of an occurrence are taken from them, so that they don't depend on the range to create schedule.
+func (c *RecurringCommand) createMonthTerms(ps RecurringMaintenance, t time.Time) []*Term {
+	firstDate := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	lastDate := firstDate.AddDate(0, 1, -1)
+
+	terms := make([]*Term, 0)
+	for _, s := range ps.RecurringSchedules {
+		terms = c.margeTerms(terms, s.CreateTerms(firstDate, lastDate))
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		return terms[i].Start.Before(terms[j].Start)
+	})
+	return terms
+}
+
+// return ordinal of the term in terms starting from 1, and count of terms
+func ordinalOfTerm(terms []*Term, t *Term) (int, int) {
+	for idx, m := range terms {
+		if t.Start.Before(m.End) && t.End.After(m.Start) {
+			return idx + 1, len(terms)
+		}
+	}
+	return 1, len(terms)
+}
+
+// return range of dates to create schedule.
+// It contains the range to prune maintenances, so that maintenances in schedule are not pruned.
+func (c *RecurringCommand) scheduleRange() (time.Time, time.Time) {
+	fromDate, toDate := c.FromDate, c.ToDate
+	pruneFrom, pruneTo := c.pruneRange()
+	if pruneFrom.Before(fromDate) {
+		fromDate = pruneFrom
+	}
+	if pruneTo.After(toDate) {
+		toDate = pruneTo
+	}
+	return fromDate, toDate
+}
+
+func (c *RecurringCommand) pruneRange() (time.Time, time.Time) {
+	fromDate, toDate := c.PruneFromDate, c.PruneToDate
+	if fromDate.IsZero() {
+		fromDate = c.FromDate
+	}
+	if toDate.IsZero() {
+		toDate = c.ToDate
+	}
+	return fromDate, toDate
+}
+
+// return true if the date of t is between fromDate and toDate (inclusive)
+func isInDateRange(t time.Time, fromDate time.Time, toDate time.Time) bool {
+	return !t.Before(fromDate) && t.Before(toDate.AddDate(0, 0, 1))
+}
+
+// return stable key of the term created by the schedule.
+// The key is a hash of service, rule of the schedule and the date of occurrence,
+// so it is not changed by title, body or merging terms.
+func createScheduleKey(service string, s RecurringSchedules, t *Term) string {
+	start, _ := time.ParseDuration(s.Start)
+	date := t.Start.Add(-start).Format(dateLayout)
+
+	rule := strings.Join([]string{
+		strings.ToLower(strings.TrimSpace(s.Day)),
+		s.Start,
+		s.Time,
+	}, " ")
+
+	hash := sha256.Sum256([]byte(strings.Join([]string{service, rule, date}, "\x00")))
+	return hex.EncodeToString(hash[:8])
 }
 
 // Marge overlapped terms
@@ -148,6 +710,21 @@ func (s *RecurringSchedules) CreateTerms(firstDate time.Time, lastDate time.Time
 	return terms
 }
 
+func (s *RecurringSchedules) validate() error {
+	if !s.isEveryDay() {
+		if _, _, err := s.weekday(); err != nil {
+			return err
+		}
+	}
+	if _, err := time.ParseDuration(s.Start); err != nil {
+		return fmt.Errorf("invalid start: %w", err)
+	}
+	if _, err := time.ParseDuration(s.Time); err != nil {
+		return fmt.Errorf("invalid time: %w", err)
+	}
+	return nil
+}
+
 func (s *RecurringSchedules) IsMaintenanceDay(base time.Time) bool {
 	if s.isEveryDay() {
 		return true
@@ -177,6 +754,9 @@ func (s *RecurringSchedules) isEveryDay() bool {
 func (s *RecurringSchedules) weekday() (ordinal int, weekday string, err error) {
 	r := regexp.MustCompile(`^\s*(1st|2nd|3rd|[4,5]+th|every)\s+(sunday|monday|tuesday|wednesday|thursday|friday|saturday)\s*$`)
 	result := r.FindAllStringSubmatch(s.Day, -1)
+	if len(result) == 0 {
+		return -1, "", fmt.Errorf("invalid weekday: %v", s.Day)
+	}
 
 	// log.Printf("%v %v", s.Day, result)
 
@@ -230,8 +810,7 @@ func (s *RecurringSchedules) ordinalOfWeekday(t time.Time) int {
 		}
 	}
 
-	log.Fatalf("Fail ordinalOfWeekday: %v", t)
-	return -1
+	panic(fmt.Sprintf("Fail ordinalOfWeekday: %v", t))
 }
 
 //-------------------------------
@@ -241,48 +820,283 @@ func (s *RecurringSchedules) ordinalOfWeekday(t time.Time) int {
 // If the schedule of maintenace which will be created by this tool is
 //  overlapped with another schedule of maintenaces which had been creaated by other way,
 // the schedule of maintenace is modified not to be overlapped to another schedule of maintenances.
+//
+// Overlapped maintenances are detected by intersection of components, and
+// how to modify is decided by ConflictPolicy for each subset of components which collide with the same maintenances.
+// Updates of other maintenances are returned for "extend-manual".
 func (c *RecurringCommand) adjustIncients(
 	incidents []StatuspageIncident,
 	schedules []ScheduledTerm,
-	config StatuspageConfig,
-) []ScheduledTerm {
+	page StatuspagePage,
+) ([]ScheduledTerm, []incidentUpdate) {
+	// incidents are copied because they are extended by "extend-manual"
+	incidents = append([]StatuspageIncident{}, incidents...)
+	updates := make([]incidentUpdate, 0)
+
 	newSchedules := make([]ScheduledTerm, 0)
 	for _, s := range schedules {
-		overlaped := false
-		for _, i := range incidents {
-			if i.isRecurringSchedule() || !s.hasSameComponent(i, config) {
+		// conflicts are resolved only for schedules to be registered, not to change other maintenances by past or far schedules
+		if !c.isToBeRegistered(s) {
+			newSchedules = append(newSchedules, s)
+			continue
+		}
+		componentIds := s.componentIdsIn(page)
+
+		// indexes of overlapped incidents for each component
+		collisions := map[string][]int{}
+		for idx, i := range incidents {
+			if c.isOwnRecurringSchedule(i) || !s.isOverlapped(i) {
 				continue
 			}
-			if s.isOverlapped(i) {
-				overlaped = true
-
-				if s.isCovered(i) {
-					fmt.Printf("skip: [%s] maintenance(%s - %s) is converted by existsing maintenance(%s - %s)\n", s.Service, s.Start, s.End, i.ScheduledFor, i.ScheduledUntil)
-				} else {
-					fmt.Printf(
-						"alert: %s(https://manage.statuspage.io/pages/%s/incidents/%s) should be modified to contain %s 〜 %s\n",
-						i.Name, i.PageId, i.Id, s.Start.Format("2006-01-02 15:04"), s.End.Format("2006-01-02 15:04"),
-					)
+			for _, id := range i.intersectComponentIds(componentIds) {
+				collisions[id] = append(collisions[id], idx)
+			}
+		}
+		if len(collisions) == 0 {
+			newSchedules = append(newSchedules, s)
+			continue
+		}
+
+		for _, subset := range groupComponentsByCollisions(componentIds, collisions) {
+			term := s.withComponents(subset.componentIds, componentIds)
+			if len(subset.incidents) == 0 {
+				newSchedules = append(newSchedules, term)
+				continue
+			}
+
+			c.warnConflict("maintenance collides with other maintenances",
+				"service", s.Service,
+				"components", page.componentNamesOf(subset.componentIds),
+				"start", s.Start,
+				"end", s.End,
+				"incidents", incidentUrls(incidents, subset.incidents),
+				"policy", c.ConflictPolicy)
+			c.countIncident(page.label(), s.Service, Operation_Conflict)
+
+			adjusted, update := c.resolveConflict(term, incidents, subset.incidents)
+			newSchedules = append(newSchedules, adjusted...)
+			if update != nil {
+				for _, idx := range subset.incidents {
+					if incidents[idx].Id == update.Incident.Id {
+						incidents[idx].ScheduledFor = *update.Request.Incident.ScheduledFor
+						incidents[idx].ScheduledUntil = *update.Request.Incident.ScheduledUntil
+					}
 				}
+				updates = mergeIncidentUpdate(updates, *update)
+			}
+		}
+	}
+	return newSchedules, updates
+}
+
+// log conflict as warning, or as debug when drifts are detected, which doesn't change any maintenance
+func (c *RecurringCommand) warnConflict(msg string, fields ...interface{}) {
+	if c.isDetectingDrifts {
+		logger.Debug(msg, fields...)
+		return
+	}
+	logger.Warn(msg, fields...)
+}
+
+// Subset of components which collide with the same incidents
+type componentCollision struct {
+	componentIds []string
+	incidents    []int
+}
+
+// group componentIds by indexes of incidents which collide with them, keeping order of componentIds
+func groupComponentsByCollisions(componentIds []string, collisions map[string][]int) []componentCollision {
+	groups := make([]componentCollision, 0)
+	for _, id := range componentIds {
+		key := fmt.Sprint(collisions[id])
+		found := false
+		for idx := range groups {
+			if fmt.Sprint(groups[idx].incidents) == key {
+				groups[idx].componentIds = append(groups[idx].componentIds, id)
+				found = true
 				break
 			}
 		}
-		if !overlaped {
-			newSchedules = append(newSchedules, s)
+		if !found {
+			groups = append(groups, componentCollision{
+				componentIds: []string{id},
+				incidents:    collisions[id],
+			})
+		}
+	}
+	return groups
+}
+
+// apply ConflictPolicy to the term which is overlapped with incidents of indexes
+func (c *RecurringCommand) resolveConflict(
+	s ScheduledTerm,
+	incidents []StatuspageIncident,
+	overlapped []int,
+) ([]ScheduledTerm, *incidentUpdate) {
+	others := incidentsOf(incidents, overlapped)
+	// parts of the term which are not covered by any of overlapped incidents. others are sorted by start.
+	remainders := s.subtract(others)
+	if len(remainders) == 0 && c.ConflictPolicy != ConflictPolicy_RegisterAnyway {
+		logger.Info("skip maintenance covered by other maintenances",
+			"service", s.Service, "start", s.Start, "end", s.End, "incidents", incidentUrls(incidents, overlapped))
+		return nil, nil
+	}
+
+	switch c.ConflictPolicy {
+	case ConflictPolicy_RegisterAnyway:
+		c.warnConflict("register maintenance overlapped with other maintenances",
+			"service", s.Service, "start", s.Start, "end", s.End, "incidents", incidentUrls(incidents, overlapped))
+		return []ScheduledTerm{s}, nil
+
+	case ConflictPolicy_Split:
+		c.logSplitTerm(s, remainders)
+		return remainders, nil
+
+	case ConflictPolicy_ExtendManual:
+		for _, i := range others {
+			if i.isRegisteredByTool() {
+				// maintenances registered by this tool (once command or other owners) are never changed,
+				// because they are registered again by their owners. The recurring maintenance is split around them.
+				logger.Info("split maintenance around maintenance registered by this tool", "service", s.Service, "incident", incidentUrl(i))
+				c.logSplitTerm(s, remainders)
+				return remainders, nil
+			}
+		}
+		// the first incident is extended to cover parts which are not covered by any of them
+		i := others[0]
+		extended := i
+		for _, r := range remainders {
+			extended = r.extend(extended)
+		}
+		logger.Info("extend other maintenance", "service", s.Service, "incident", incidentUrl(i), "start", extended.ScheduledFor, "end", extended.ScheduledUntil)
+		return nil, &incidentUpdate{
+			Incident: i,
+			Request: StatuspageUpdateIncidentRequest{
+				Incident: StatuspageIncidentUpdate{
+					ScheduledFor:   &extended.ScheduledFor,
+					ScheduledUntil: &extended.ScheduledUntil,
+				},
+			},
+		}
+
+	default:
+		c.warnConflict("other maintenances should be modified to contain maintenance",
+			"service", s.Service, "incidents", incidentUrls(incidents, overlapped), "start", s.Start, "end", s.End)
+		return nil, nil
+	}
+}
+
+// return copy of incidents of indexes
+func incidentsOf(incidents []StatuspageIncident, indexes []int) []StatuspageIncident {
+	result := make([]StatuspageIncident, 0, len(indexes))
+	for _, idx := range indexes {
+		result = append(result, incidents[idx])
+	}
+	return result
+}
+
+func (c *RecurringCommand) logSplitTerm(s ScheduledTerm, remainders []ScheduledTerm) {
+	for _, r := range remainders {
+		logger.Info("split maintenance", "service", s.Service, "start", s.Start, "end", s.End, "splitStart", r.Start, "splitEnd", r.End)
+	}
+}
+
+func incidentUrls(incidents []StatuspageIncident, indexes []int) string {
+	urls := make([]string, 0, len(indexes))
+	for _, idx := range indexes {
+		urls = append(urls, incidentUrl(incidents[idx]))
+	}
+	return strings.Join(urls, ", ")
+}
+
+// return url of the incident in the console of Statuspage
+func incidentUrl(i StatuspageIncident) string {
+	return fmt.Sprintf("https://manage.statuspage.io/pages/%s/incidents/%s", i.PageId, i.Id)
+}
+
+// add update to updates. An update of the same incident is replaced.
+func mergeIncidentUpdate(updates []incidentUpdate, update incidentUpdate) []incidentUpdate {
+	for idx, u := range updates {
+		if u.Incident.Id == update.Incident.Id {
+			update.Incident = u.Incident
+			updates[idx] = update
+			return updates
+		}
+	}
+	return append(updates, update)
+}
+
+// return parts of the term which are not overlapped with incidents.
+// Keys of the parts are derived from the key of the term.
+func (s ScheduledTerm) subtract(incidents []StatuspageIncident) []ScheduledTerm {
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidents[i].ScheduledFor.Before(incidents[j].ScheduledFor)
+	})
+
+	remainders := make([]ScheduledTerm, 0)
+	start := s.Start
+	for _, i := range incidents {
+		if i.ScheduledFor.After(start) {
+			end := i.ScheduledFor
+			if end.After(s.End) {
+				end = s.End
+			}
+			remainders = append(remainders, s.part(start, end))
+		}
+		if i.ScheduledUntil.After(start) {
+			start = i.ScheduledUntil
 		}
 	}
-	return newSchedules
+	if start.Before(s.End) {
+		remainders = append(remainders, s.part(start, s.End))
+	}
+
+	for idx := range remainders {
+		remainders[idx].Key = fmt.Sprintf("%s-%d", s.Key, idx+1)
+	}
+	return remainders
+}
+
+func (s ScheduledTerm) part(start time.Time, end time.Time) ScheduledTerm {
+	s.Start = start
+	s.End = end
+	return s
+}
+
+// return the incident which is extended to contain the term
+func (s ScheduledTerm) extend(i StatuspageIncident) StatuspageIncident {
+	if s.Start.Before(i.ScheduledFor) {
+		i.ScheduledFor = s.Start
+	}
+	if s.End.After(i.ScheduledUntil) {
+		i.ScheduledUntil = s.End
+	}
+	return i
 }
 
-func (s *ScheduledTerm) hasSameComponent(incident StatuspageIncident, config StatuspageConfig) bool {
-	component := config.findComponentByServiceName(s.Service)
+// return componentIds of the term. componentIds of the service are returned if the term doesn't have them.
+func (s ScheduledTerm) componentIdsIn(page StatuspagePage) []string {
+	if s.ComponentIds != nil {
+		return s.ComponentIds
+	}
+	component := page.findComponentByServiceName(s.Service)
 	if component == nil {
-		log.Fatalf("unkown service is found: %s", s.Service)
+		return []string{}
 	}
-	if incident.isSameComponentIds(component.ComponentIds) {
-		return true
+	return component.ComponentIds
+}
+
+// return the term for a subset of componentIds.
+// Key of the term is derived from the key of the original term and the subset.
+func (s ScheduledTerm) withComponents(componentIds []string, allComponentIds []string) ScheduledTerm {
+	s.ComponentIds = componentIds
+	if len(componentIds) != len(allComponentIds) {
+		sorted := append([]string{}, componentIds...)
+		sort.Strings(sorted)
+		hash := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+		s.Key = fmt.Sprintf("%s-%s", s.Key, hex.EncodeToString(hash[:4]))
 	}
-	return false
+	return s
 }
 
 func (s ScheduledTerm) isOverlapped(i StatuspageIncident) bool {
@@ -290,89 +1104,264 @@ func (s ScheduledTerm) isOverlapped(i StatuspageIncident) bool {
 		(i.ScheduledUntil.Equal(s.Start) || i.ScheduledUntil.After(s.Start))
 }
 
-func (s ScheduledTerm) isCovered(i StatuspageIncident) bool {
-	return (i.ScheduledFor.Equal(s.Start) || i.ScheduledFor.Before(s.Start)) &&
-		(i.ScheduledUntil.Equal(s.End) || i.ScheduledUntil.After(s.End))
-}
-
 //-------------------------------
 // Register schedule of maintenancee
 //-------------------------------
 
-func (c *RecurringCommand) getStatuspageRepository(pageId string) StatuspageRepository {
+func (c *RecurringCommand) getStatuspageRepository(page StatuspagePage) StatuspageRepository {
+	if c.repositoryOf != nil {
+		return c.repositoryOf(page)
+	}
+	accessToken := page.accessToken(c.AccessToken)
 	if c.isDryRun {
-		return createStatuspageDryRunRepository(pageId, c.AccessToken)
+		return createStatuspageDryRunRepository(page.StatuspagePageId, accessToken)
+	} else if c.auditLog != nil {
+		return createStatuspageAuditRepository(createStatuspageRESTRepository(page.StatuspagePageId, accessToken), c.auditLog, page.StatuspagePageId)
 	} else {
-		return createStatuspageRESTRepository(pageId, c.AccessToken)
+		return createStatuspageRESTRepository(page.StatuspagePageId, accessToken)
+	}
+}
+
+func (c *RecurringCommand) updateIncidents(
+	repository StatuspageRepository,
+	page StatuspagePage,
+	updates []incidentUpdate,
+) error {
+	for _, u := range updates {
+		// name and times after the update are recorded
+		name, start, end := u.Incident.Name, u.Incident.ScheduledFor, u.Incident.ScheduledUntil
+		if u.Request.Incident.Name != "" {
+			name = u.Request.Incident.Name
+		}
+		if u.Request.Incident.ScheduledFor != nil {
+			start = *u.Request.Incident.ScheduledFor
+		}
+		if u.Request.Incident.ScheduledUntil != nil {
+			end = *u.Request.Incident.ScheduledUntil
+		}
+
+		c.logOperation("update maintenance", "service", page.serviceOf(u.Incident), "name", u.Incident.Name, "incidentId", u.Incident.Id, "update", u.Request.Incident)
+		err := repository.Update(u.Incident, u.Request)
+		if err != nil {
+			err = fmt.Errorf("failed to updateIncidents: %w", err)
+		} else if s := c.state.find(page.StatuspagePageId, u.Incident.Id); s != nil {
+			c.state.put(s.apply(u.Request))
+		}
+		err = c.record(OperationResult{
+			Page:       page.label(),
+			Service:    page.serviceOf(u.Incident),
+			Operation:  Operation_Update,
+			Name:       name,
+			IncidentId: u.Incident.Id,
+			Start:      start,
+			End:        end,
+			Err:        err,
+		})
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (c *RecurringCommand) deleteIncidents(
 	repository StatuspageRepository,
 	incidents []StatuspageIncident,
-	config StatuspageConfig,
+	page StatuspagePage,
 	schedules []ScheduledTerm,
-) {
+) error {
+	pruneFrom, pruneTo := c.pruneRange()
+
 	toBeDeleted := make([]StatuspageIncident, 0)
 	for _, i := range incidents {
-		exsists := false
+		if !c.isOwnRecurringSchedule(i) || !i.isScheduled() {
+			continue
+		}
+
+		matched := false
+		recreated := false
 		for _, s := range schedules {
-			if c.existsSameIncident([]StatuspageIncident{i}, config, s) {
-				exsists = true
+			incident, diff := c.findSameIncident([]StatuspageIncident{i}, page, s)
+			if incident == nil {
+				continue
+			}
+			matched = true
+			// deleted to be registered again
+			recreated = diff.needsRecreate() && c.isToBeRegistered(s)
+			if !diff.needsRecreate() {
 				break
 			}
 		}
 
-		if !exsists &&
-			i.isRecurringSchedule() &&
-			i.ScheduledFor.After(c.FromDate) &&
-			i.ScheduledFor.Before(c.ToDate) {
+		if recreated ||
+			(!matched && !c.NoPrune && isInDateRange(i.ScheduledFor, pruneFrom, pruneTo)) {
 			toBeDeleted = append(toBeDeleted, i)
 		}
 	}
-	for _, i := range toBeDeleted {
+	return c.removeIncidents(repository, page, toBeDeleted, Operation_Delete)
+}
+
+// return incidents without duplicates, and duplicated incidents to be deleted.
+// Own scheduled incidents are duplicated if they have the same scheduleKey,
+// or the same components and term when they don't have scheduleKey.
+// The oldest incident by CreatedAt is kept.
+func (c *RecurringCommand) findDuplicateIncidents(incidents []StatuspageIncident) ([]StatuspageIncident, []StatuspageIncident) {
+	candidates := make([]StatuspageIncident, 0)
+	for _, i := range incidents {
+		if c.isOwnRecurringSchedule(i) && i.isScheduled() {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if !candidates[a].CreatedAt.Equal(candidates[b].CreatedAt) {
+			return candidates[a].CreatedAt.Before(candidates[b].CreatedAt)
+		}
+		return candidates[a].Id < candidates[b].Id
+	})
+
+	seen := map[string]bool{}
+	duplicated := map[string]bool{}
+	duplicates := make([]StatuspageIncident, 0)
+	for _, i := range candidates {
+		// incidents with schedule keys are matched only by the keys,
+		// because services which have the same components have the same term with different keys
+		key := "term:" + i.termKey()
+		if scheduleKey := i.scheduleKey(); scheduleKey != "" {
+			key = "key:" + scheduleKey
+		}
+		if seen[key] {
+			duplicated[i.Id] = true
+			duplicates = append(duplicates, i)
+			continue
+		}
+		seen[key] = true
+	}
+
+	kept := make([]StatuspageIncident, 0, len(incidents)-len(duplicates))
+	for _, i := range incidents {
+		if !duplicated[i.Id] {
+			kept = append(kept, i)
+		}
+	}
+	return kept, duplicates
+}
+
+// delete duplicated incidents in the prune range, and return duplicated incidents which are not deleted.
+// see findDuplicateIncidents
+func (c *RecurringCommand) deleteDuplicateIncidents(
+	repository StatuspageRepository,
+	page StatuspagePage,
+	duplicates []StatuspageIncident,
+) ([]StatuspageIncident, error) {
+	pruneFrom, pruneTo := c.pruneRange()
+
+	toBeDeleted := make([]StatuspageIncident, 0)
+	notDeleted := make([]StatuspageIncident, 0)
+	for _, i := range duplicates {
+		if c.NoPrune || !isInDateRange(i.ScheduledFor, pruneFrom, pruneTo) {
+			logger.Warn("duplicated maintenance is not deleted out of prune range",
+				"page", page.label(), "incidentId", i.Id, "name", i.Name, "start", i.ScheduledFor, "end", i.ScheduledUntil, "noPrune", c.NoPrune)
+			notDeleted = append(notDeleted, i)
+			continue
+		}
+		logger.Warn("duplicated maintenance will be deleted",
+			"page", page.label(), "incidentId", i.Id, "name", i.Name, "start", i.ScheduledFor, "end", i.ScheduledUntil, "createdAt", i.CreatedAt)
+		toBeDeleted = append(toBeDeleted, i)
+	}
+	return notDeleted, c.removeIncidents(repository, page, toBeDeleted, Operation_DeleteDuplicate)
+}
+
+// delete incidents and record results as the operation
+func (c *RecurringCommand) removeIncidents(
+	repository StatuspageRepository,
+	page StatuspagePage,
+	incidents []StatuspageIncident,
+	operation string,
+) error {
+	for _, i := range incidents {
+		c.logOperation("delete maintenance", "service", page.serviceOf(i), "name", i.Name, "incidentId", i.Id, "start", i.ScheduledFor, "end", i.ScheduledUntil)
 		err := repository.Delete(i)
 		if err != nil {
-			log.Fatalf("failed to deleteIncidents: %v", err)
+			err = fmt.Errorf("failed to deleteIncidents: %w", err)
+		} else {
+			c.state.remove(page.StatuspagePageId, i.Id)
+		}
+		err = c.record(OperationResult{
+			Page:       page.label(),
+			Service:    page.serviceOf(i),
+			Operation:  operation,
+			Name:       i.Name,
+			IncidentId: i.Id,
+			Start:      i.ScheduledFor,
+			End:        i.ScheduledUntil,
+			Err:        err,
+		})
+		if err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
 func (c *RecurringCommand) registerIncidents(
 	repository StatuspageRepository,
 	incidents []StatuspageIncident,
-	config StatuspageConfig,
+	page StatuspagePage,
 	schedules []ScheduledTerm,
-) {
+) error {
 	toBeRegistered := make([]ScheduledTerm, 0)
+	registered := make([]ScheduledTerm, 0)
 	for _, s := range schedules {
-		if !c.existsSameIncident(incidents, config, s) &&
-			s.Start.After(c.FromDate) &&
-			s.Start.Before(c.ToDate.AddDate(0, 0, 1)) &&
-			s.Start.After(time.Now()) {
+		exists := c.existsSameIncident(incidents, page, s)
+		if exists {
+			registered = append(registered, s)
+		}
+		if !exists && c.isToBeRegistered(s) {
 			toBeRegistered = append(toBeRegistered, s)
 		} else {
-			fmt.Printf("skip: [%s] %s - %s\n", s.Service, s.Start, s.End)
+			logger.Debug("skip maintenance", "service", s.Service, "start", s.Start, "end", s.End)
+			c.countIncident(page.label(), s.Service, Operation_Skip)
 		}
 	}
+	// schedules which failed to be registered are not counted
+	defer func() { c.setScheduledWindows(page, registered) }()
 
 	for _, s := range toBeRegistered {
-		component := config.findComponentByServiceName(s.Service)
-		if component == nil {
-			log.Fatalf("unkown service is found: %s", s.Service)
-		}
-
-		err := repository.Add(CreateMaintenanceStatuspageData(
+		c.logOperation("add maintenance", "service", s.Service, "name", s.Title, "start", s.Start, "end", s.End)
+		incident, err := repository.Add(CreateMaintenanceStatuspageData(
 			s.Title,
 			s.Body,
-			component.ComponentIds,
+			s.componentIdsIn(page),
 			s.Start,
 			s.End,
+			s.Options.merge(page.MaintenanceDefaults),
 			ScheduleType_Recurring,
-			"",
+			s.Key,
+			c.Owner,
 		))
+		if err == nil {
+			registered = append(registered, s)
+			c.state.put(StateIncident{
+				PageId:         page.StatuspagePageId,
+				IncidentId:     incident.Id,
+				ScheduleKey:    s.Key,
+				Name:           s.Title,
+				ScheduledFor:   s.Start,
+				ScheduledUntil: s.End,
+			})
+		}
+		err = c.record(OperationResult{
+			Page:       page.label(),
+			Service:    s.Service,
+			Operation:  Operation_Add,
+			Name:       s.Title,
+			IncidentId: incident.Id,
+			Start:      s.Start,
+			End:        s.End,
+			Err:        err,
+		})
 		if err != nil {
-			log.Fatalf("[ERORR] %s", err.Error())
+			return err
 		}
 
 		if !c.isDryRun {
@@ -380,20 +1369,94 @@ func (c *RecurringCommand) registerIncidents(
 			time.Sleep(time.Second * 5)
 		}
 	}
+	return nil
 }
 
-func (c *RecurringCommand) existsSameIncident(incidents []StatuspageIncident, config StatuspageConfig, schedule ScheduledTerm) bool {
-	component := config.findComponentByServiceName(schedule.Service)
-	if component == nil {
-		log.Fatalf("unkown service is found: %s", schedule.Service)
+// return true if the schedule starts between FromDate and ToDate, and in the future or within GracePeriod
+func (c *RecurringCommand) isToBeRegistered(s ScheduledTerm) bool {
+	return isInDateRange(s.Start, c.FromDate, c.ToDate) && s.Start.After(c.currentTime().Add(-c.GracePeriod))
+}
+
+func (c *RecurringCommand) currentTime() time.Time {
+	if c.now != nil {
+		return c.now()
 	}
+	return time.Now()
+}
+
+// return true if the incident is a recurring schedule created by the owner of this command.
+// recurring schedules of other owners are treated like maintenances created by other way.
+func (c *RecurringCommand) isOwnRecurringSchedule(incident StatuspageIncident) bool {
+	return incident.isRecurringSchedule() && incident.isOwnedBy(c.Owner)
+}
+
+// return true if an incident which corresponds to the schedule exists and doesn't need to be registered again
+func (c *RecurringCommand) existsSameIncident(incidents []StatuspageIncident, page StatuspagePage, schedule ScheduledTerm) bool {
+	incident, diff := c.findSameIncident(incidents, page, schedule)
+	// maintenances which have started are never registered again
+	return incident != nil && (!diff.needsRecreate() || !incident.isScheduled())
+}
 
-	for _, incident := range incidents {
-		if incident.isSameComponentIds(component.ComponentIds) &&
+// return the incident which corresponds to the schedule, and difference between them.
+// Incidents are matched by scheduleKey, or by components and term if they don't have scheduleKey.
+// Difference is empty for incidents which are not created by this command.
+func (c *RecurringCommand) findSameIncident(incidents []StatuspageIncident, page StatuspagePage, schedule ScheduledTerm) (*StatuspageIncident, IncidentDiff) {
+	componentIds := schedule.componentIdsIn(page)
+
+	for idx, incident := range incidents {
+		// incidents created by older versions don't have scheduleKey
+		if key := incident.scheduleKey(); key != "" {
+			if key == schedule.Key && incident.isOwnedBy(c.Owner) {
+				return &incidents[idx], diffIncident(incident, schedule, componentIds)
+			}
+			continue
+		}
+
+		if incident.isSameComponentIds(componentIds) &&
 			incident.isSameTerm(schedule.Start, schedule.End) {
-			return true
+			if !c.isOwnRecurringSchedule(incident) {
+				return &incidents[idx], IncidentDiff{}
+			}
+			return &incidents[idx], diffIncident(incident, schedule, componentIds)
 		}
 	}
 
-	return false
+	return nil, IncidentDiff{}
+}
+
+// print differences between schedules and incidents, and return updates of incidents
+// whose title, body or components are changed. Incidents whose term is changed are registered again.
+func (c *RecurringCommand) diffIncidents(incidents []StatuspageIncident, page StatuspagePage, schedules []ScheduledTerm) []incidentUpdate {
+	updates := make([]incidentUpdate, 0)
+	for _, s := range schedules {
+		incident, diff := c.findSameIncident(incidents, page, s)
+		if incident == nil || diff.IsEmpty() {
+			continue
+		}
+
+		if !incident.isScheduled() {
+			request, ok := diff.inProgressUpdateRequest(s.componentIdsIn(page))
+			if !ok || !incident.isInProgress() {
+				continue
+			}
+			if !c.UpdateInProgress {
+				logger.Info("skip maintenance in progress", "service", s.Service, "incidentId", incident.Id, "diff", diff)
+				continue
+			}
+			logger.Info("maintenance in progress will be updated", "service", s.Service, "incidentId", incident.Id, "diff", diff)
+			updates = append(updates, incidentUpdate{Incident: *incident, Request: request})
+			continue
+		}
+
+		if diff.needsRecreate() {
+			logger.Info("maintenance will be registered again", "service", s.Service, "incidentId", incident.Id, "diff", diff)
+			continue
+		}
+		logger.Info("maintenance will be updated", "service", s.Service, "incidentId", incident.Id, "diff", diff)
+		updates = append(updates, incidentUpdate{
+			Incident: *incident,
+			Request:  diff.updateRequest(s.componentIdsIn(page)),
+		})
+	}
+	return updates
 }
@@ -1,6 +1,9 @@
 package maintenance
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -15,6 +18,30 @@ func timeOf(year int, month time.Month, day, hour, min int) time.Time {
 	return time.Date(year, month, day, hour, min, 0, 0, loc)
 }
 
+// return StatuspageIncident of component c1 registered by recurring command.
+// scheduleKey and owner are not written in metadata if they are empty, like maintenances registered by older versions.
+func recurringIncidentOf(id string, key string, owner string, start time.Time, end time.Time) StatuspageIncident {
+	metadata := map[string]interface{}{key_scheduleType: ScheduleType_Recurring}
+	if key != "" {
+		metadata[key_scheduleKey] = key
+	}
+	if owner != "" {
+		metadata[key_owner] = owner
+	}
+	return StatuspageIncident{
+		Id:             id,
+		Components:     []StatuspageComponnet{{Id: "c1"}},
+		Metadata:       map[string]map[string]interface{}{key_toolNamespace: metadata},
+		ScheduledFor:   start,
+		ScheduledUntil: end,
+	}
+}
+
+// return the clock of RecurringCommand fixed at t
+func nowOf(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
 func TestCreateSchedule(t *testing.T) {
 	patterns := []struct {
 		schedule    RecurringCommand       // input
@@ -144,7 +171,11 @@ func TestCreateSchedule(t *testing.T) {
 	}
 
 	for idx, row := range patterns {
-		result := row.schedule.CreateSchedule(row.maintenance)
+		result, err := row.schedule.CreateSchedule(row.maintenance, StatuspagePage{})
+		if err != nil {
+			t.Errorf(`test(%v): %v.CreateSchedule(%v) returns error: %v`, idx+1, row.schedule, row.maintenance, err)
+			continue
+		}
 
 		if len(row.exp) != len(result) {
 			t.Errorf(`test(%v): %v.CreateSchedule(%v)'s len is %v. exp is %v`,
@@ -536,9 +567,10 @@ func TestAdjustIncients(t *testing.T) {
 	commmand := RecurringCommand{
 		FromDate: dateOf(2020, 1, 1),
 		ToDate:   dateOf(2020, 1, 5),
+		now:      nowOf(dateOf(2019, 12, 31)),
 	}
 
-	config := StatuspageConfig{
+	config := StatuspagePage{
 		StatuspagePageId: "testPageId",
 		StatuspageServices: []StatuspageService{
 			{
@@ -773,7 +805,7 @@ func TestAdjustIncients(t *testing.T) {
 	}
 
 	for idx, row := range patterns {
-		actual := commmand.adjustIncients(row.incidents, row.schduledTerms, config)
+		actual, _ := commmand.adjustIncients(row.incidents, row.schduledTerms, config)
 		if len(actual) != len(row.exp) {
 			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
 		} else {
@@ -787,3 +819,1268 @@ func TestAdjustIncients(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateScheduleTemplate(t *testing.T) {
+	command := RecurringCommand{
+		FromDate: dateOf(2020, 1, 1),
+		ToDate:   dateOf(2020, 1, 2),
+	}
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "test", Description: "Test Service", ComponentIds: []string{"c1", "c2"}},
+		},
+		componentNames: map[string]string{"c1": "API"},
+	}
+	maintenances := []RecurringMaintenance{
+		{
+			Service: "test",
+			Title:   LocalizedText{noLocale: "{{.Description}} ({{.Ordinal}} of {{.Count}})"},
+			Body:    LocalizedText{noLocale: `{{join .Components ", "}} until {{(in "America/Los_Angeles" .End).Format "15:04 MST"}} ({{.Duration}})`},
+			RecurringSchedules: []RecurringSchedules{
+				{
+					Day:   "everyday",
+					Start: "10h00m",
+					Time:  "20m",
+				},
+			},
+		},
+	}
+
+	result, err := command.CreateSchedule(maintenances, page)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("exp:2, actual:%v", len(result))
+	}
+	if result[1].Title != "Test Service (2 of 31)" {
+		t.Errorf("unexpected title: %v", result[1].Title)
+	}
+	if result[0].Body != "API, c2 until 17:20 PST (20m0s)" {
+		t.Errorf("unexpected body: %v", result[0].Body)
+	}
+}
+
+func TestCreateScheduleOrdinal(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "test", ComponentIds: []string{"c1"}},
+		},
+	}
+	maintenances := []RecurringMaintenance{
+		{
+			Service: "test",
+			Title:   LocalizedText{noLocale: "{{.Ordinal}} of {{.Count}}"},
+			RecurringSchedules: []RecurringSchedules{
+				{Day: "every monday", Start: "10h00m", Time: "20m"},
+				{Day: "3rd friday", Start: "10h00m", Time: "20m"},
+			},
+		},
+	}
+
+	titles := map[string]string{}
+	for _, from := range []time.Time{dateOf(2020, 1, 1), dateOf(2020, 1, 8), dateOf(2020, 1, 20)} {
+		command := RecurringCommand{FromDate: from, ToDate: from.AddDate(0, 0, 30)}
+		result, err := command.CreateSchedule(maintenances, page)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, s := range result {
+			if title, ok := titles[s.Key]; ok && title != s.Title {
+				t.Errorf("title of %v is changed by fromDate %v: %v -> %v", s.Start, from, title, s.Title)
+			}
+			titles[s.Key] = s.Title
+		}
+	}
+
+	// mondays of 2020/1 are 6, 13, 20 and 27, and 3rd friday is 17
+	patterns := []struct {
+		start time.Time // input
+		title string    // expected
+	}{
+		{timeOf(2020, 1, 6, 10, 0), "1 of 5"},
+		{timeOf(2020, 1, 17, 10, 0), "3 of 5"},
+		{timeOf(2020, 1, 27, 10, 0), "5 of 5"},
+		{timeOf(2020, 2, 3, 10, 0), "1 of 5"},
+	}
+	command := RecurringCommand{FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 2, 29)}
+	result, err := command.CreateSchedule(maintenances, page)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for idx, row := range patterns {
+		found := false
+		for _, s := range result {
+			if s.Start.Equal(row.start) {
+				found = true
+				if s.Title != row.title {
+					t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.title, s.Title)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("test(%v): %v is not scheduled", idx+1, row.start)
+		}
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	patterns := []struct {
+		text  string // input
+		isErr bool   // expected
+	}{
+		{"Maintenance of {{.Service}}", false},
+		{"{{.Start.Format \"2006-01-02\"}} - {{(in \"UTC\" .End).Format \"15:04\"}}", false},
+		{"{{.Unknown}}", true},
+		{"{{.Service", true},
+		{"{{in \"Unknown/Zone\" .Start}}", true},
+	}
+
+	for idx, row := range patterns {
+		err := validateTemplate("title", row.text)
+		if row.isErr != (err != nil) {
+			t.Errorf("test(%v): %q returns %v", idx+1, row.text, err)
+		}
+	}
+}
+
+func TestLoadBody(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "body.md"), []byte("# Maintenance\n- {{.Service}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := []struct {
+		maintenance RecurringMaintenance // input
+		exp         LocalizedText        // expected
+		isErr       bool                 // expected
+	}{
+		// relative path
+		{
+			RecurringMaintenance{Service: "test", BodyFile: LocalizedText{noLocale: "body.md"}},
+			LocalizedText{noLocale: "# Maintenance\n- {{.Service}}\n"},
+			false,
+		},
+
+		// merged with body of other locale
+		{
+			RecurringMaintenance{Service: "test", Body: LocalizedText{"ja": "メンテナンス"}, BodyFile: LocalizedText{"en": "body.md"}},
+			LocalizedText{"ja": "メンテナンス", "en": "# Maintenance\n- {{.Service}}\n"},
+			false,
+		},
+
+		// both body and bodyFile
+		{
+			RecurringMaintenance{Service: "test", Body: LocalizedText{noLocale: "body"}, BodyFile: LocalizedText{noLocale: "body.md"}},
+			nil,
+			true,
+		},
+
+		// file not found
+		{
+			RecurringMaintenance{Service: "test", BodyFile: LocalizedText{noLocale: "notfound.md"}},
+			nil,
+			true,
+		},
+	}
+
+	for idx, row := range patterns {
+		actual, err := row.maintenance.loadBody(dir)
+		if row.isErr {
+			if err == nil {
+				t.Errorf("test(%v): error is expected", idx+1)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test(%v): unexpected error: %v", idx+1, err)
+			continue
+		}
+		if len(actual) != len(row.exp) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+			continue
+		}
+		for locale, text := range row.exp {
+			if actual[locale] != text {
+				t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+			}
+		}
+	}
+}
+
+func TestScheduleKey(t *testing.T) {
+	command := RecurringCommand{
+		FromDate: dateOf(2020, 1, 1),
+		ToDate:   dateOf(2020, 1, 2),
+	}
+	maintenance := RecurringMaintenance{
+		Service: "test",
+		Title:   LocalizedText{noLocale: "title"},
+		RecurringSchedules: []RecurringSchedules{
+			{Day: "everyday", Start: "10h00m", Time: "20m"},
+		},
+	}
+
+	result1, err := command.CreateSchedule([]RecurringMaintenance{maintenance}, StatuspagePage{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// key is not changed by title
+	maintenance.Title = LocalizedText{noLocale: "new title"}
+	result2, err := command.CreateSchedule([]RecurringMaintenance{maintenance}, StatuspagePage{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// key is changed by service
+	maintenance.Service = "renamed"
+	result3, err := command.CreateSchedule([]RecurringMaintenance{maintenance}, StatuspagePage{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result1[0].Key == "" || result1[0].Key == result1[1].Key {
+		t.Errorf("keys should be unique for each occurrence: %v, %v", result1[0].Key, result1[1].Key)
+	}
+	if result1[0].Key != result2[0].Key {
+		t.Errorf("key should be stable: %v, %v", result1[0].Key, result2[0].Key)
+	}
+	if result1[0].Key == result3[0].Key {
+		t.Errorf("key should be changed by service: %v", result1[0].Key)
+	}
+}
+
+func TestExistsSameIncident(t *testing.T) {
+	command := RecurringCommand{}
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+			{Service: "service2", ComponentIds: []string{"c1"}},
+		},
+	}
+	schedule := ScheduledTerm{
+		Key:     "key1",
+		Service: "service1",
+		Start:   timeOf(2020, 1, 1, 1, 0),
+		End:     timeOf(2020, 1, 1, 2, 0),
+	}
+
+	patterns := []struct {
+		incident StatuspageIncident // input
+		exp      bool               // expected
+	}{
+		// same key
+		{recurringIncidentOf("", "key1", "", timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 2, 0)), true},
+
+		// another key with same components and term
+		{recurringIncidentOf("", "key2", "", timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 2, 0)), false},
+
+		// created by older version
+		{recurringIncidentOf("", "", "", timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 2, 0)), true},
+	}
+
+	for idx, row := range patterns {
+		if actual := command.existsSameIncident([]StatuspageIncident{row.incident}, page, schedule); actual != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+	}
+}
+
+// StatuspageRepository which records operations
+type fakeRepository struct {
+	incidents  []StatuspageIncident
+	active     []StatuspageIncident
+	others     []StatuspageIncident // incidents which are not listed
+	components []StatuspageComponnet
+	added      []StatuspageCreateIncidentRequest
+	deleted    []StatuspageIncident
+	updated    []StatuspageUpdateIncidentRequest
+}
+
+func (r *fakeRepository) Add(data StatuspageCreateIncidentRequest) (StatuspageIncident, error) {
+	r.added = append(r.added, data)
+	return StatuspageIncident{Id: fmt.Sprintf("added%d", len(r.added))}, nil
+}
+
+func (r *fakeRepository) Delete(incident StatuspageIncident) error {
+	r.deleted = append(r.deleted, incident)
+	return nil
+}
+
+func (r *fakeRepository) Update(incident StatuspageIncident, data StatuspageUpdateIncidentRequest) error {
+	r.updated = append(r.updated, data)
+	return nil
+}
+
+func (r *fakeRepository) FindAllScheduledIncidents(page int, perPage int) ([]StatuspageIncident, error) {
+	return r.incidents, nil
+}
+
+func (r *fakeRepository) FindAllActiveMaintenances(page int, perPage int) ([]StatuspageIncident, error) {
+	return r.active, nil
+}
+
+func (r *fakeRepository) FindIncident(incidentId string) (*StatuspageIncident, error) {
+	for _, i := range append(r.incidents, r.active...) {
+		if i.Id == incidentId {
+			return &i, nil
+		}
+	}
+	for _, i := range r.others {
+		if i.Id == incidentId {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) FindAllComponents(page int, perPage int) ([]StatuspageComponnet, error) {
+	return r.components, nil
+}
+
+func TestDeleteIncidentsOfOwner(t *testing.T) {
+	command := RecurringCommand{
+		FromDate: dateOf(2020, 1, 1),
+		ToDate:   dateOf(2020, 1, 5),
+		Owner:    "teamA",
+	}
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+		},
+	}
+	incidents := []StatuspageIncident{
+		recurringIncidentOf("1", "", "teamA", timeOf(2020, 1, 2, 1, 0), timeOf(2020, 1, 2, 2, 0)),
+		recurringIncidentOf("2", "", "teamB", timeOf(2020, 1, 2, 1, 0), timeOf(2020, 1, 2, 2, 0)),
+		recurringIncidentOf("3", "", "", timeOf(2020, 1, 2, 1, 0), timeOf(2020, 1, 2, 2, 0)),
+	}
+
+	repository := &fakeRepository{}
+	if err := command.deleteIncidents(repository, incidents, page, []ScheduledTerm{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repository.deleted) != 1 || repository.deleted[0].Id != "1" {
+		t.Errorf("only incidents of the owner should be deleted: %v", repository.deleted)
+	}
+}
+
+func TestDeleteIncidentsPruneRange(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+		},
+	}
+	incidents := []StatuspageIncident{
+		recurringIncidentOf("first", "", "", timeOf(2020, 1, 1, 0, 0), timeOf(2020, 1, 1, 1, 0)),
+		recurringIncidentOf("last", "", "", timeOf(2020, 1, 5, 23, 0), timeOf(2020, 1, 6, 0, 0)),
+		recurringIncidentOf("after", "", "", timeOf(2020, 1, 6, 1, 0), timeOf(2020, 1, 6, 2, 0)),
+		recurringIncidentOf("later", "", "", timeOf(2020, 2, 1, 1, 0), timeOf(2020, 2, 1, 2, 0)),
+	}
+
+	patterns := []struct {
+		command RecurringCommand // input
+		exp     []string         // expected ids of deleted incidents
+	}{
+		{
+			RecurringCommand{FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 5)},
+			[]string{"first", "last"},
+		},
+		{
+			RecurringCommand{FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 5), PruneToDate: dateOf(2020, 1, 31)},
+			[]string{"first", "last", "after"},
+		},
+		{
+			RecurringCommand{FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 5), PruneFromDate: dateOf(2020, 1, 6), PruneToDate: dateOf(2020, 2, 1)},
+			[]string{"after", "later"},
+		},
+		{
+			RecurringCommand{FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 5), NoPrune: true},
+			[]string{},
+		},
+	}
+
+	for idx, row := range patterns {
+		repository := &fakeRepository{}
+		if err := row.command.deleteIncidents(repository, incidents, page, []ScheduledTerm{}); err != nil {
+			t.Fatalf("test(%v): unexpected error: %v", idx+1, err)
+		}
+		actual := make([]string, 0)
+		for _, i := range repository.deleted {
+			actual = append(actual, i.Id)
+		}
+		if fmt.Sprint(actual) != fmt.Sprint(row.exp) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+	}
+}
+
+func TestDuplicateIncidents(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1", "c2"}},
+		},
+	}
+	inRange := RecurringCommand{Owner: "team1", FromDate: dateOf(2020, 2, 1), ToDate: dateOf(2020, 2, 1)}
+	noPrune := RecurringCommand{Owner: "team1", FromDate: dateOf(2020, 2, 1), ToDate: dateOf(2020, 2, 1), NoPrune: true}
+	outOfRange := RecurringCommand{Owner: "team1", FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 31)}
+
+	patterns := []struct {
+		command    RecurringCommand     // input
+		incidents  []StatuspageIncident // input
+		duplicates []string             // expected
+		deleted    []string             // expected
+	}{
+		// same schedule key. the oldest is kept
+		{
+			inRange,
+			[]StatuspageIncident{
+				{
+					Id:             "newer",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 2, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "older",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c2"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+			},
+			[]string{"newer"},
+			[]string{"newer"},
+		},
+		// same components and term without schedule key
+		{
+			inRange,
+			[]StatuspageIncident{
+				{
+					Id:             "1",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}, {Id: "c2"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "2",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c2"}, {Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 2, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "3",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 3, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+			},
+			[]string{"2"},
+			[]string{"2"},
+		},
+		// same components and term with different schedule keys, e.g. services which have the same components
+		{
+			inRange,
+			[]StatuspageIncident{
+				{
+					Id:             "1",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "2",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key2", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 2, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+			},
+			[]string{},
+			[]string{},
+		},
+		// same components and term with and without schedule key
+		{
+			inRange,
+			[]StatuspageIncident{
+				{
+					Id:             "1",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "2",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key2", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 2, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+			},
+			[]string{},
+			[]string{},
+		},
+		// incidents of other owners and in progress are not duplicates
+		{
+			inRange,
+			[]StatuspageIncident{
+				{
+					Id:             "1",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 2, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "2",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team2"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "3",
+					Status:         IncidentStatus_InProgress,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+			},
+			[]string{},
+			[]string{},
+		},
+		// duplicates are not deleted with no-prune, or out of prune range
+		{
+			noPrune,
+			[]StatuspageIncident{
+				{
+					Id:             "newer",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 2, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "older",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+			},
+			[]string{"newer"},
+			[]string{},
+		},
+		{
+			outOfRange,
+			[]StatuspageIncident{
+				{
+					Id:             "newer",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 2, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+				{
+					Id:             "older",
+					Status:         IncidentStatus_Scheduled,
+					Components:     []StatuspageComponnet{{Id: "c1"}},
+					Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "key1", key_owner: "team1"}},
+					CreatedAt:      timeOf(2020, 1, 1, 0, 0),
+					ScheduledFor:   timeOf(2020, 2, 1, 1, 0),
+					ScheduledUntil: timeOf(2020, 2, 1, 2, 0),
+				},
+			},
+			[]string{"newer"},
+			[]string{},
+		},
+	}
+
+	for idx, row := range patterns {
+		command := row.command
+		kept, duplicates := command.findDuplicateIncidents(row.incidents)
+		if len(kept)+len(duplicates) != len(row.incidents) {
+			t.Errorf("test(%v): all incidents should be kept or deleted: %v, %v", idx+1, kept, duplicates)
+		}
+		ids := make([]string, 0)
+		for _, i := range duplicates {
+			ids = append(ids, i.Id)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(row.duplicates) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.duplicates, ids)
+		}
+
+		repository := &fakeRepository{}
+		notDeleted, err := command.deleteDuplicateIncidents(repository, page, duplicates)
+		if err != nil {
+			t.Fatalf("test(%v): unexpected error: %v", idx+1, err)
+		}
+		actual := make([]string, 0)
+		for _, i := range repository.deleted {
+			actual = append(actual, i.Id)
+		}
+		if fmt.Sprint(actual) != fmt.Sprint(row.deleted) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.deleted, actual)
+		}
+		if len(actual)+len(notDeleted) != len(duplicates) {
+			t.Errorf("test(%v): duplicates should be deleted or returned: %v", idx+1, notDeleted)
+		}
+		for _, result := range command.report.Results {
+			if result.Operation != Operation_DeleteDuplicate {
+				t.Errorf("test(%v): exp:%v, actual:%v", idx+1, Operation_DeleteDuplicate, result.Operation)
+			}
+		}
+	}
+}
+
+func TestDuplicateIncidentsSharedComponents(t *testing.T) {
+	// services which have the same components are registered in the same term with different schedule keys
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+			{Service: "service2", ComponentIds: []string{"c1"}},
+		},
+	}
+	tomorrow := dateIn(time.Now()).AddDate(0, 0, 1)
+	command := RecurringCommand{
+		FromDate: tomorrow,
+		ToDate:   tomorrow,
+		isDryRun: true, // to skip waiting for rate limit
+	}
+	schedules := []ScheduledTerm{
+		{Key: "key1", Service: "service1", Title: "title", Start: tomorrow.Add(time.Hour), End: tomorrow.Add(2 * time.Hour)},
+		{Key: "key2", Service: "service2", Title: "title", Start: tomorrow.Add(time.Hour), End: tomorrow.Add(2 * time.Hour)},
+	}
+
+	repository := &fakeRepository{}
+	command.repositoryOf = func(page StatuspagePage) StatuspageRepository { return repository }
+	if err := command.reconcilePage(page, schedules); err != nil {
+		t.Fatal(err)
+	}
+	if len(repository.added) != 2 {
+		t.Fatalf("exp:2, actual:%v", len(repository.added))
+	}
+
+	// registered maintenances are neither deleted nor registered again
+	incidents := make([]StatuspageIncident, 0)
+	for idx, added := range repository.added {
+		request := added.Incident
+		incidents = append(incidents, StatuspageIncident{
+			Id:              fmt.Sprintf("%d", idx+1),
+			Name:            request.Name,
+			Status:          IncidentStatus_Scheduled,
+			Components:      []StatuspageComponnet{{Id: "c1"}},
+			IncidentUpdates: []StatuspageIncidentUpdateEntry{{Body: request.Body}},
+			Metadata:        map[string]map[string]interface{}{key_toolNamespace: request.Metadata[key_toolNamespace].(map[string]interface{})},
+			CreatedAt:       timeOf(2020, 1, idx+1, 0, 0),
+			ScheduledFor:    request.ScheduledFor,
+			ScheduledUntil:  request.ScheduledUntil,
+		})
+	}
+	for run := 2; run <= 3; run++ {
+		repository = &fakeRepository{incidents: incidents}
+		if err := command.reconcilePage(page, schedules); err != nil {
+			t.Fatal(err)
+		}
+		if len(repository.added) != 0 || len(repository.deleted) != 0 {
+			t.Errorf("run(%v): nothing should be changed: added:%v, deleted:%v", run, repository.added, repository.deleted)
+		}
+	}
+}
+
+func TestValidateMaintenances(t *testing.T) {
+	config := StatuspageConfig{
+		StatuspagePageId:   "page1",
+		StatuspageServices: []StatuspageService{{Service: "s", ComponentIds: []string{"c1"}}},
+	}
+	schedule := RecurringSchedules{Day: "every monday", Start: "10h00m", Time: "20m"}
+
+	patterns := []struct {
+		maintenance RecurringMaintenance // input
+		isErr       bool                 // expected
+	}{
+		{RecurringMaintenance{Service: "s", Title: LocalizedText{noLocale: "title"}, RecurringSchedules: []RecurringSchedules{schedule}}, false},
+
+		// unknown service
+		{RecurringMaintenance{Service: "unknown", Title: LocalizedText{noLocale: "title"}}, true},
+		// invalid template
+		{RecurringMaintenance{Service: "s", Title: LocalizedText{noLocale: "{{.Unknown}}"}}, true},
+		// invalid options
+		{RecurringMaintenance{Service: "s", Title: LocalizedText{noLocale: "title"}, Options: MaintenanceOptions{Impact: "unknown"}}, true},
+		// invalid schedules
+		{RecurringMaintenance{Service: "s", Title: LocalizedText{noLocale: "title"}, RecurringSchedules: []RecurringSchedules{{Day: "6th monday", Start: "10h00m", Time: "20m"}}}, true},
+		{RecurringMaintenance{Service: "s", Title: LocalizedText{noLocale: "title"}, RecurringSchedules: []RecurringSchedules{{Day: "everyday", Start: "10:00", Time: "20m"}}}, true},
+	}
+
+	for idx, row := range patterns {
+		err := validateMaintenances([]RecurringMaintenance{row.maintenance}, config, t.TempDir())
+		if row.isErr != (err != nil) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.isErr, err)
+		} else if err != nil && ExitCode(err) != ExitCodeConfigError {
+			t.Errorf("test(%v): ConfigError is expected: %v", idx+1, err)
+		}
+	}
+}
+
+func TestValidateConflictPolicy(t *testing.T) {
+	patterns := []struct {
+		policy string // input
+		isErr  bool   // expected
+	}{
+		{"", false},
+		{ConflictPolicy_Skip, false},
+		{ConflictPolicy_Split, false},
+		{ConflictPolicy_ExtendManual, false},
+		{ConflictPolicy_RegisterAnyway, false},
+		{"unknown", true},
+	}
+
+	for idx, row := range patterns {
+		if err := validateConflictPolicy(row.policy); row.isErr != (err != nil) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.isErr, err)
+		}
+	}
+}
+
+// repository which fails to add the first maintenance
+type failingAddRepository struct {
+	fakeRepository
+	failed bool
+}
+
+func (r *failingAddRepository) Add(data StatuspageCreateIncidentRequest) (StatuspageIncident, error) {
+	if !r.failed {
+		r.failed = true
+		return StatuspageIncident{}, &APIError{Method: "POST", Url: "/incidents", StatusCode: 500}
+	}
+	return r.fakeRepository.Add(data)
+}
+
+func TestContinueOnError(t *testing.T) {
+	dir := t.TempDir()
+	scheduleFilename := filepath.Join(dir, "schedule.yaml")
+	statuspageFilename := filepath.Join(dir, "statuspage.yaml")
+	schedule := `
+- service: ServiceA
+  title: "Maintenance of {{.Service}}"
+  recurring:
+    - day: everyday
+      start: 10h00m
+      time: 20m
+`
+	statuspage := `
+statuspagePageId: page1
+statuspageServices:
+  - service: ServiceA
+    componentIds: ["c1"]
+`
+	if err := os.WriteFile(scheduleFilename, []byte(schedule), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(statuspageFilename, []byte(statuspage), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tomorrow := dateIn(time.Now()).AddDate(0, 0, 1)
+
+	patterns := []struct {
+		continueOnError bool // input
+		expCode         int  // expected
+		expAdded        int  // expected
+	}{
+		{false, ExitCodeAPIError, 0},
+		{true, ExitCodePartialFailure, 2},
+	}
+
+	for idx, row := range patterns {
+		repository := &failingAddRepository{}
+		command := RecurringCommand{
+			ScheduleFilename:   scheduleFilename,
+			StatuspageFilename: statuspageFilename,
+			FromDate:           tomorrow,
+			ToDate:             tomorrow.AddDate(0, 0, 2),
+			ContinueOnError:    row.continueOnError,
+			isDryRun:           true, // to skip waiting for rate limit
+			repositoryOf: func(page StatuspagePage) StatuspageRepository {
+				return repository
+			},
+		}
+		err := command.Run()
+		if code := ExitCode(err); code != row.expCode {
+			t.Errorf("test(%v): exp:%v, actual:%v %v", idx+1, row.expCode, code, err)
+		}
+		if len(repository.added) != row.expAdded {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.expAdded, len(repository.added))
+		}
+	}
+}
+
+func TestScheduleRange(t *testing.T) {
+	patterns := []struct {
+		command RecurringCommand // input
+		expFrom time.Time        // expected
+		expTo   time.Time        // expected
+	}{
+		{
+			RecurringCommand{FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 5)},
+			dateOf(2020, 1, 1),
+			dateOf(2020, 1, 5),
+		},
+		{
+			RecurringCommand{FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 5), PruneFromDate: dateOf(2019, 12, 1), PruneToDate: dateOf(2020, 1, 31)},
+			dateOf(2019, 12, 1),
+			dateOf(2020, 1, 31),
+		},
+	}
+
+	for idx, row := range patterns {
+		from, to := row.command.scheduleRange()
+		if !from.Equal(row.expFrom) || !to.Equal(row.expTo) {
+			t.Errorf("test(%v): exp:%v - %v, actual:%v - %v", idx+1, row.expFrom, row.expTo, from, to)
+		}
+	}
+}
+
+func TestInProgressIncidents(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+		},
+	}
+	// end time is extended
+	schedules := []ScheduledTerm{
+		{
+			Key:     "key1",
+			Service: "service1",
+			Start:   timeOf(2020, 1, 2, 1, 0),
+			End:     timeOf(2020, 1, 2, 3, 0),
+		},
+	}
+
+	patterns := []struct {
+		status           string // input
+		updateInProgress bool   // input
+		expDeleted       int    // expected
+		expUpdated       int    // expected
+		expExists        bool   // expected
+	}{
+		{IncidentStatus_Scheduled, false, 0, 0, false},
+		{IncidentStatus_InProgress, false, 0, 0, true},
+		{IncidentStatus_InProgress, true, 0, 1, true},
+		{IncidentStatus_Verifying, true, 0, 1, true},
+		{IncidentStatus_Completed, true, 0, 0, true},
+	}
+
+	for idx, row := range patterns {
+		command := RecurringCommand{
+			FromDate:         dateOf(2020, 1, 1),
+			ToDate:           dateOf(2020, 1, 5),
+			UpdateInProgress: row.updateInProgress,
+		}
+		incident := recurringIncidentOf(row.status, "key1", "", timeOf(2020, 1, 2, 1, 0), timeOf(2020, 1, 2, 2, 0))
+		incident.Status = row.status
+		incidents := []StatuspageIncident{incident}
+
+		repository := &fakeRepository{}
+		if err := command.deleteIncidents(repository, incidents, page, schedules); err != nil {
+			t.Fatalf("test(%v): unexpected error: %v", idx+1, err)
+		}
+		if len(repository.deleted) != row.expDeleted {
+			t.Errorf("test(%v): deleted exp:%v, actual:%v", idx+1, row.expDeleted, len(repository.deleted))
+		}
+
+		updates := command.diffIncidents(incidents, page, schedules)
+		if len(updates) != row.expUpdated {
+			t.Errorf("test(%v): updated exp:%v, actual:%v", idx+1, row.expUpdated, len(updates))
+		} else if len(updates) > 0 {
+			end := updates[0].Request.Incident.ScheduledUntil
+			if end == nil || !end.Equal(schedules[0].End) || updates[0].Request.Incident.ScheduledFor != nil {
+				t.Errorf("test(%v): only end time should be updated: %v", idx+1, updates[0].Request)
+			}
+		}
+
+		if actual := command.existsSameIncident(incidents, page, schedules[0]); actual != row.expExists {
+			t.Errorf("test(%v): exists exp:%v, actual:%v", idx+1, row.expExists, actual)
+		}
+	}
+}
+
+func TestIsToBeRegisteredGracePeriod(t *testing.T) {
+	now := time.Now()
+	schedule := ScheduledTerm{Start: now.Add(-10 * time.Minute), End: now.Add(time.Hour)}
+
+	patterns := []struct {
+		gracePeriod time.Duration // input
+		exp         bool          // expected
+	}{
+		{0, false},
+		{5 * time.Minute, false},
+		{30 * time.Minute, true},
+	}
+
+	for idx, row := range patterns {
+		command := RecurringCommand{
+			FromDate:    now.AddDate(0, 0, -1),
+			ToDate:      now.AddDate(0, 0, 1),
+			GracePeriod: row.gracePeriod,
+		}
+		if actual := command.isToBeRegistered(schedule); actual != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+	}
+}
+
+func TestAdjustIncientsConflictPolicy(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "testService", ComponentIds: []string{"testComponentId"}},
+		},
+	}
+	manual := func(id string, start time.Time, end time.Time) StatuspageIncident {
+		return StatuspageIncident{
+			Id:             id,
+			Components:     []StatuspageComponnet{{Id: "testComponentId"}},
+			ScheduledFor:   start,
+			ScheduledUntil: end,
+		}
+	}
+	schedules := []ScheduledTerm{
+		{
+			Key:     "key",
+			Service: "testService",
+			Start:   timeOf(2020, 1, 1, 1, 0),
+			End:     timeOf(2020, 1, 1, 2, 0),
+		},
+	}
+	incidents := []StatuspageIncident{
+		manual("1", timeOf(2020, 1, 1, 1, 10), timeOf(2020, 1, 1, 1, 20)),
+		manual("2", timeOf(2020, 1, 1, 1, 40), timeOf(2020, 1, 1, 2, 30)),
+	}
+
+	patterns := []struct {
+		policy     string // input
+		exp        []Term // expected schedules
+		expUpdates []Term // expected updates
+	}{
+		{
+			ConflictPolicy_Skip,
+			[]Term{},
+			[]Term{},
+		},
+		{
+			ConflictPolicy_Split,
+			[]Term{
+				{timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 1, 10)},
+				{timeOf(2020, 1, 1, 1, 20), timeOf(2020, 1, 1, 1, 40)},
+			},
+			[]Term{},
+		},
+		{
+			// the first maintenance is extended until the second one
+			ConflictPolicy_ExtendManual,
+			[]Term{},
+			[]Term{
+				{timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 1, 40)},
+			},
+		},
+		{
+			ConflictPolicy_RegisterAnyway,
+			[]Term{
+				{timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 2, 0)},
+			},
+			[]Term{},
+		},
+	}
+
+	for idx, row := range patterns {
+		command := RecurringCommand{ConflictPolicy: row.policy, FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 1), now: nowOf(dateOf(2019, 12, 31))}
+		actual, updates := command.adjustIncients(incidents, schedules, page)
+
+		if len(actual) != len(row.exp) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		} else {
+			for i := range row.exp {
+				if !row.exp[i].Start.Equal(actual[i].Start) || !row.exp[i].End.Equal(actual[i].End) {
+					t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+				}
+				if actual[i].Key == "" {
+					t.Errorf("test(%v): key is empty", idx+1)
+				}
+			}
+		}
+
+		if len(updates) != len(row.expUpdates) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.expUpdates, updates)
+		} else {
+			for i := range row.expUpdates {
+				u := updates[i].Request.Incident
+				if !row.expUpdates[i].Start.Equal(*u.ScheduledFor) || !row.expUpdates[i].End.Equal(*u.ScheduledUntil) {
+					t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.expUpdates, u)
+				}
+			}
+		}
+	}
+}
+
+func TestAdjustIncientsCoveredByIncidents(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "testService", ComponentIds: []string{"testComponentId"}},
+		},
+	}
+	schedules := []ScheduledTerm{
+		{Key: "key", Service: "testService", Start: timeOf(2020, 1, 1, 1, 0), End: timeOf(2020, 1, 1, 2, 0)},
+	}
+	// each of them covers only a part, but the schedule is covered by both of them
+	incidents := []StatuspageIncident{
+		{Id: "1", Components: []StatuspageComponnet{{Id: "testComponentId"}}, ScheduledFor: timeOf(2020, 1, 1, 1, 30), ScheduledUntil: timeOf(2020, 1, 1, 2, 0)},
+		{Id: "2", Components: []StatuspageComponnet{{Id: "testComponentId"}}, ScheduledFor: timeOf(2020, 1, 1, 0, 30), ScheduledUntil: timeOf(2020, 1, 1, 1, 30)},
+	}
+
+	patterns := []struct {
+		policy string // input
+		exp    int    // expected count of schedules
+	}{
+		{ConflictPolicy_Skip, 0},
+		{ConflictPolicy_Split, 0},
+		{ConflictPolicy_ExtendManual, 0},
+		{ConflictPolicy_RegisterAnyway, 1},
+	}
+
+	for idx, row := range patterns {
+		command := RecurringCommand{ConflictPolicy: row.policy, FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 1), now: nowOf(dateOf(2019, 12, 31))}
+		actual, updates := command.adjustIncients(incidents, schedules, page)
+		if len(actual) != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+		if len(updates) != 0 {
+			t.Errorf("test(%v): nothing should be updated: %v", idx+1, updates)
+		}
+	}
+}
+
+func TestUpdateIncidentsResult(t *testing.T) {
+	page := StatuspagePage{StatuspageServices: []StatuspageService{{Service: "testService", ComponentIds: []string{"c1"}}}}
+	incident := StatuspageIncident{
+		Id:             "1",
+		Name:           "manual",
+		Components:     []StatuspageComponnet{{Id: "c1"}},
+		ScheduledFor:   timeOf(2020, 1, 1, 1, 10),
+		ScheduledUntil: timeOf(2020, 1, 1, 1, 20),
+	}
+	start := timeOf(2020, 1, 1, 1, 0)
+	end := timeOf(2020, 1, 1, 2, 0)
+	updates := []incidentUpdate{{
+		Incident: incident,
+		Request:  StatuspageUpdateIncidentRequest{Incident: StatuspageIncidentUpdate{ScheduledFor: &start, ScheduledUntil: &end}},
+	}}
+
+	command := RecurringCommand{}
+	if err := command.updateIncidents(&fakeRepository{}, page, updates); err != nil {
+		t.Fatal(err)
+	}
+
+	// times after the update are recorded
+	results := command.report.Results
+	if len(results) != 1 || results[0].Name != "manual" || !results[0].Start.Equal(start) || !results[0].End.Equal(end) {
+		t.Errorf("unexpected results: %v", results)
+	}
+}
+
+func TestAdjustIncientsOneoff(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "testService", ComponentIds: []string{"testComponentId"}},
+		},
+	}
+	schedules := []ScheduledTerm{
+		{Key: "key", Service: "testService", Start: timeOf(2020, 1, 1, 1, 0), End: timeOf(2020, 1, 1, 2, 0)},
+	}
+	oneoff := StatuspageIncident{
+		Id:             "oneoff",
+		Components:     []StatuspageComponnet{{Id: "testComponentId"}},
+		Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Oneoff}},
+		ScheduledFor:   timeOf(2020, 1, 1, 1, 10),
+		ScheduledUntil: timeOf(2020, 1, 1, 1, 20),
+	}
+
+	// maintenance registered by once command is not extended, and the recurring maintenance is split around it
+	command := RecurringCommand{ConflictPolicy: ConflictPolicy_ExtendManual, FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 1), now: nowOf(dateOf(2019, 12, 31))}
+	actual, updates := command.adjustIncients([]StatuspageIncident{oneoff}, schedules, page)
+
+	exp := []Term{
+		{timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 1, 10)},
+		{timeOf(2020, 1, 1, 1, 20), timeOf(2020, 1, 1, 2, 0)},
+	}
+	if len(updates) != 0 {
+		t.Errorf("oneoff maintenance should not be updated: %v", updates)
+	}
+	if len(actual) != len(exp) {
+		t.Fatalf("exp:%v, actual:%v", exp, actual)
+	}
+	for i := range exp {
+		if !exp[i].Start.Equal(actual[i].Start) || !exp[i].End.Equal(actual[i].End) {
+			t.Errorf("exp:%v, actual:%v", exp, actual)
+		}
+	}
+}
+
+func TestAdjustIncientsOtherOwner(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "testService", ComponentIds: []string{"testComponentId"}},
+		},
+	}
+	schedules := []ScheduledTerm{
+		{Key: "key", Service: "testService", Start: timeOf(2020, 1, 1, 1, 0), End: timeOf(2020, 1, 1, 2, 0)},
+	}
+	others := StatuspageIncident{
+		Id:             "teamB",
+		Components:     []StatuspageComponnet{{Id: "testComponentId"}},
+		Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_owner: "teamB"}},
+		ScheduledFor:   timeOf(2020, 1, 1, 1, 10),
+		ScheduledUntil: timeOf(2020, 1, 1, 1, 20),
+	}
+
+	// recurring maintenance of other owner is not extended, because it's registered again by its owner
+	command := RecurringCommand{Owner: "teamA", ConflictPolicy: ConflictPolicy_ExtendManual, FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 1), now: nowOf(dateOf(2019, 12, 31))}
+	actual, updates := command.adjustIncients([]StatuspageIncident{others}, schedules, page)
+
+	exp := []Term{
+		{timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 1, 10)},
+		{timeOf(2020, 1, 1, 1, 20), timeOf(2020, 1, 1, 2, 0)},
+	}
+	if len(updates) != 0 {
+		t.Errorf("maintenance of other owner should not be updated: %v", updates)
+	}
+	if len(actual) != len(exp) {
+		t.Fatalf("exp:%v, actual:%v", exp, actual)
+	}
+	for i := range exp {
+		if !exp[i].Start.Equal(actual[i].Start) || !exp[i].End.Equal(actual[i].End) {
+			t.Errorf("exp:%v, actual:%v", exp, actual)
+		}
+	}
+}
+
+func TestAdjustIncientsNotToBeRegistered(t *testing.T) {
+	m := newTestMetrics(t)
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "testService", ComponentIds: []string{"testComponentId"}},
+		},
+	}
+	schedules := []ScheduledTerm{
+		{Key: "key", Service: "testService", Start: timeOf(2020, 1, 2, 1, 0), End: timeOf(2020, 1, 2, 2, 0)},
+	}
+	incidents := []StatuspageIncident{
+		{Id: "1", Components: []StatuspageComponnet{{Id: "testComponentId"}}, ScheduledFor: timeOf(2020, 1, 2, 1, 10), ScheduledUntil: timeOf(2020, 1, 2, 1, 20)},
+	}
+
+	patterns := []struct {
+		command RecurringCommand // input
+	}{
+		// in the range of pruning, but not of registering
+		{RecurringCommand{ConflictPolicy: ConflictPolicy_ExtendManual, FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 1), PruneToDate: dateOf(2020, 1, 31), now: nowOf(dateOf(2019, 12, 31))}},
+		// already started
+		{RecurringCommand{ConflictPolicy: ConflictPolicy_ExtendManual, FromDate: dateOf(2020, 1, 2), ToDate: dateOf(2020, 1, 2), now: nowOf(timeOf(2020, 1, 2, 1, 30))}},
+		// started before grace period
+		{RecurringCommand{ConflictPolicy: ConflictPolicy_ExtendManual, FromDate: dateOf(2020, 1, 2), ToDate: dateOf(2020, 1, 2), GracePeriod: 10 * time.Minute, now: nowOf(timeOf(2020, 1, 2, 1, 30))}},
+	}
+
+	for idx, row := range patterns {
+		actual, updates := row.command.adjustIncients(incidents, schedules, page)
+		// schedules are kept as they are, and other maintenances are not extended
+		if len(actual) != 1 || !actual[0].Start.Equal(schedules[0].Start) || !actual[0].End.Equal(schedules[0].End) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, schedules, actual)
+		}
+		if len(updates) != 0 {
+			t.Errorf("test(%v): nothing should be updated: %v", idx+1, updates)
+		}
+	}
+
+	// conflicts are not counted
+	if count := m.incidents.get("", "testService", Operation_Conflict); count != 0 {
+		t.Errorf("exp:%v, actual:%v", 0, count)
+	}
+}
+
+func TestAdjustIncientsComponentSubset(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "testService", ComponentIds: []string{"c1", "c2", "c3"}},
+		},
+	}
+	schedules := []ScheduledTerm{
+		{
+			Key:     "key",
+			Service: "testService",
+			Start:   timeOf(2020, 1, 1, 1, 0),
+			End:     timeOf(2020, 1, 1, 2, 0),
+		},
+	}
+	incidents := []StatuspageIncident{
+		// manual maintenance on shared component c2 and another component
+		{
+			Id:             "1",
+			Components:     []StatuspageComponnet{{Id: "c2"}, {Id: "other"}},
+			ScheduledFor:   timeOf(2020, 1, 1, 1, 30),
+			ScheduledUntil: timeOf(2020, 1, 1, 3, 0),
+		},
+	}
+
+	command := RecurringCommand{ConflictPolicy: ConflictPolicy_Split, FromDate: dateOf(2020, 1, 1), ToDate: dateOf(2020, 1, 1), now: nowOf(dateOf(2019, 12, 31))}
+	actual, _ := command.adjustIncients(incidents, schedules, page)
+
+	exp := []ScheduledTerm{
+		{ComponentIds: []string{"c1", "c3"}, Start: timeOf(2020, 1, 1, 1, 0), End: timeOf(2020, 1, 1, 2, 0)},
+		{ComponentIds: []string{"c2"}, Start: timeOf(2020, 1, 1, 1, 0), End: timeOf(2020, 1, 1, 1, 30)},
+	}
+	if len(actual) != len(exp) {
+		t.Fatalf("exp:%v, actual:%v", exp, actual)
+	}
+	for i := range exp {
+		if fmt.Sprint(exp[i].ComponentIds) != fmt.Sprint(actual[i].ComponentIds) ||
+			!exp[i].Start.Equal(actual[i].Start) || !exp[i].End.Equal(actual[i].End) {
+			t.Errorf("exp:%v, actual:%v", exp[i], actual[i])
+		}
+	}
+	if actual[0].Key == actual[1].Key || actual[0].Key == "key" {
+		t.Errorf("keys of subsets should be unique: %v, %v", actual[0].Key, actual[1].Key)
+	}
+}
@@ -0,0 +1,343 @@
+package maintenance
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command which serves JSON API to show schedules and register maintenances
+type ServeCommand struct {
+	Addr      string
+	Token     string           // bearer token to access the API
+	Recurring RecurringCommand // options of plan and apply. FromDate and ToDate are taken from each request
+	Day       int              // default days of terms from today
+
+	mu sync.Mutex // plan and apply are executed one by one
+
+	applyMu   sync.Mutex       // guards applying and lastApply
+	applying  bool             // true while apply is executed in background
+	lastApply *reconcileResult // result of the last apply
+}
+
+// timeouts of HTTP server
+const (
+	serverReadHeaderTimeout = 10 * time.Second
+	serverReadTimeout       = 30 * time.Second
+	serverWriteTimeout      = 5 * time.Minute // plan executes the whole reconcile in the request
+	serverIdleTimeout       = 2 * time.Minute
+)
+
+// status of apply
+const (
+	ApplyStatus_Running = "running"
+	ApplyStatus_Done    = "done"
+)
+
+// a page and its occurrences of maintenance
+type pageOccurrences struct {
+	Page        string          `json:"page"`
+	Occurrences []ScheduledTerm `json:"occurrences"`
+}
+
+// a page and its registered maintenances
+type pageIncidents struct {
+	Page      string               `json:"page"`
+	Incidents []StatuspageIncident `json:"incidents"`
+}
+
+type operationView struct {
+	Page       string    `json:"page"`
+	Operation  string    `json:"operation"`
+	Name       string    `json:"name"`
+	IncidentId string    `json:"incidentId,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// response of plan and apply
+type reconcileResult struct {
+	DryRun     bool            `json:"dryRun"`
+	Status     string          `json:"status,omitempty"` // status of apply: running or done
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Operations []operationView `json:"operations"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// execute serve command
+func (c *ServeCommand) Run() error {
+	if c.Token == "" {
+		return configErrorf("token of API is required")
+	}
+	logger.Info("listening", "addr", c.Addr)
+	return newHTTPServer(c.Addr, c.handler()).ListenAndServe()
+}
+
+// return HTTP server with timeouts, so that slow clients don't hold connections
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		ReadTimeout:       serverReadTimeout,
+		WriteTimeout:      serverWriteTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+}
+
+func (c *ServeCommand) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", c.handleHealthz)
+	mux.Handle("/metrics", metrics.handler())
+	mux.Handle("/maintenances", c.authorize(http.MethodGet, c.handleMaintenances))
+	mux.Handle("/occurrences", c.authorize(http.MethodGet, c.handleOccurrences))
+	mux.Handle("/incidents", c.authorize(http.MethodGet, c.handleIncidents))
+	mux.Handle("/plan", c.authorize(http.MethodPost, c.handlePlan))
+	mux.Handle("/apply", c.authorize(http.MethodPost, c.handleApply))
+	mux.Handle("/apply/status", c.authorize(http.MethodGet, c.handleApplyStatus))
+	return mux
+}
+
+// return handler which accepts only requests of the method with the bearer token
+func (c *ServeCommand) authorize(method string, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.Token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: fmt.Sprintf("method %s is not allowed", r.Method)})
+			return
+		}
+		handler(w, r)
+	})
+}
+
+func (c *ServeCommand) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// list maintenances in schedule file
+func (c *ServeCommand) handleMaintenances(w http.ResponseWriter, r *http.Request) {
+	maintenances := make([]RecurringMaintenance, 0)
+	if err := loadFromFile(c.Recurring.ScheduleFilename, &maintenances); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, maintenances)
+}
+
+// list occurrences of maintenances between from and from+day-1
+func (c *ServeCommand) handleOccurrences(w http.ResponseWriter, r *http.Request) {
+	command, err := c.recurringCommand(r, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	pages, schedules, err := command.load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result := make([]pageOccurrences, 0, len(pages))
+	for i, page := range pages {
+		result = append(result, pageOccurrences{Page: page.label(), Occurrences: schedules[i]})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// list maintenances registered in Statuspage
+func (c *ServeCommand) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	config := StatuspageConfig{}
+	if err := loadFromFile(c.Recurring.StatuspageFilename, &config); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := config.validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result := make([]pageIncidents, 0)
+	for _, page := range config.Pages() {
+		repository := c.Recurring.getStatuspageRepository(page)
+		incidents, err := repository.FindAllScheduledIncidents(1, 200)
+		if err != nil {
+			writeError(w, fmt.Errorf("FindAllScheduledIncidents err: %w", err))
+			return
+		}
+		active, err := repository.FindAllActiveMaintenances(1, 100)
+		if err != nil {
+			writeError(w, fmt.Errorf("FindAllActiveMaintenances err: %w", err))
+			return
+		}
+		result = append(result, pageIncidents{Page: page.label(), Incidents: append(active, incidents...)})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// show operations to register maintenances between from and from+day-1. Nothing is changed.
+func (c *ServeCommand) handlePlan(w http.ResponseWriter, r *http.Request) {
+	command, err := c.recurringCommand(r, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, err := c.reconcile(command)
+	status := http.StatusOK
+	if err != nil {
+		status = httpStatusOf(err)
+	}
+	writeJSON(w, status, result)
+}
+
+// start to register maintenances between from and from+day-1 in background, because it takes seconds for each maintenance.
+// The result is returned by /apply/status.
+func (c *ServeCommand) handleApply(w http.ResponseWriter, r *http.Request) {
+	command, err := c.recurringCommand(r, false)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	c.applyMu.Lock()
+	if c.applying {
+		c.applyMu.Unlock()
+		writeJSON(w, http.StatusConflict, errorResponse{Error: "apply is in progress"})
+		return
+	}
+	c.applying = true
+	accepted := c.newReconcileResult(command)
+	accepted.Status = ApplyStatus_Running
+	c.lastApply = &accepted
+	c.applyMu.Unlock()
+
+	go func() {
+		result, _ := c.reconcile(command)
+		result.Status = ApplyStatus_Done
+
+		c.applyMu.Lock()
+		c.applying = false
+		c.lastApply = &result
+		c.applyMu.Unlock()
+	}()
+
+	writeJSON(w, http.StatusAccepted, accepted)
+}
+
+// return the result of the last apply
+func (c *ServeCommand) handleApplyStatus(w http.ResponseWriter, r *http.Request) {
+	c.applyMu.Lock()
+	result := c.lastApply
+	c.applyMu.Unlock()
+
+	if result == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "apply has not been executed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// execute the command, and return its result with the error of the command
+func (c *ServeCommand) reconcile(command *RecurringCommand) (reconcileResult, error) {
+	c.mu.Lock()
+	err := command.Run()
+	c.mu.Unlock()
+
+	result := c.newReconcileResult(command)
+	for _, o := range command.report.Results {
+		view := operationView{
+			Page:       o.Page,
+			Operation:  o.Operation,
+			Name:       o.Name,
+			IncidentId: o.IncidentId,
+			Start:      o.Start,
+			End:        o.End,
+		}
+		if o.Err != nil {
+			view.Error = o.Err.Error()
+		}
+		result.Operations = append(result.Operations, view)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, err
+}
+
+func (c *ServeCommand) newReconcileResult(command *RecurringCommand) reconcileResult {
+	return reconcileResult{
+		DryRun:     command.isDryRun,
+		From:       command.FromDate.Format(dateLayout),
+		To:         command.ToDate.Format(dateLayout),
+		Operations: make([]operationView, 0, len(command.report.Results)),
+	}
+}
+
+// return RecurringCommand for the range of `from` and `day` query parameters.
+// from is today and day is Day of the command if they are not specified.
+func (c *ServeCommand) recurringCommand(r *http.Request, dryRun bool) (*RecurringCommand, error) {
+	fromDate := dateIn(time.Now())
+	if from := r.URL.Query().Get("from"); from != "" {
+		var err error
+		fromDate, err = time.ParseInLocation(dateLayout, from, loc)
+		if err != nil {
+			return nil, configErrorf("invalid from: %s", err)
+		}
+	}
+
+	day := c.Day
+	if value := r.URL.Query().Get("day"); value != "" {
+		var err error
+		day, err = strconv.Atoi(value)
+		if err != nil || day <= 0 {
+			return nil, configErrorf("invalid day: %s", value)
+		}
+	}
+
+	command := c.Recurring
+	// apply is also executed in dry run when serve command is in dry run
+	command.isDryRun = dryRun || c.Recurring.isDryRun
+	command.ContinueOnError = true
+	command.FromDate = fromDate
+	command.ToDate = fromDate.AddDate(0, 0, day-1)
+	return &command, nil
+}
+
+// return status code of the response for the error
+func httpStatusOf(err error) int {
+	switch ExitCode(err) {
+	case ExitCodeConfigError:
+		return http.StatusBadRequest
+	case ExitCodeAPIError, ExitCodePartialFailure:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, httpStatusOf(err), errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to write response", "err", err)
+	}
+}
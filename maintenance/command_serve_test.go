@@ -0,0 +1,160 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestServeCommand(t *testing.T, repository *fakeRepository) *ServeCommand {
+	dir := t.TempDir()
+	schedule := `
+- service: ServiceA
+  title: "Maintenance of {{.Service}}"
+  recurring:
+    - day: everyday
+      start: 10h00m
+      time: 20m
+`
+	statuspage := `
+statuspagePageId: page1
+statuspageServices:
+  - service: ServiceA
+    componentIds: ["c1"]
+`
+	scheduleFilename := filepath.Join(dir, "schedule.yaml")
+	statuspageFilename := filepath.Join(dir, "statuspage.yaml")
+	if err := os.WriteFile(scheduleFilename, []byte(schedule), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(statuspageFilename, []byte(statuspage), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return &ServeCommand{
+		Token: "secret",
+		Day:   30,
+		Recurring: RecurringCommand{
+			ScheduleFilename:   scheduleFilename,
+			StatuspageFilename: statuspageFilename,
+			repositoryOf: func(page StatuspagePage) StatuspageRepository {
+				return repository
+			},
+		},
+	}
+}
+
+func TestServeAuthorization(t *testing.T) {
+	handler := newTestServeCommand(t, &fakeRepository{}).handler()
+
+	patterns := []struct {
+		method string // input
+		path   string // input
+		token  string // input
+		exp    int    // expected
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/maintenances", "", http.StatusUnauthorized},
+		{http.MethodGet, "/maintenances", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/maintenances", "secret", http.StatusOK},
+		{http.MethodPost, "/maintenances", "secret", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/apply", "secret", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/occurrences?from=invalid", "secret", http.StatusBadRequest},
+	}
+
+	for idx, row := range patterns {
+		req := httptest.NewRequest(row.method, row.path, nil)
+		if row.token != "" {
+			req.Header.Set("Authorization", "Bearer "+row.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, rec.Code)
+		}
+	}
+}
+
+func TestServeOccurrencesAndPlan(t *testing.T) {
+	repository := &fakeRepository{}
+	handler := newTestServeCommand(t, repository).handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/occurrences?from=2020-01-01&day=3", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var occurrences []pageOccurrences
+	if err := json.Unmarshal(rec.Body.Bytes(), &occurrences); err != nil {
+		t.Fatalf("invalid response: %s", rec.Body.String())
+	}
+	if len(occurrences) != 1 || len(occurrences[0].Occurrences) != 3 {
+		t.Fatalf("3 occurrences are expected: %s", rec.Body.String())
+	}
+	if title := occurrences[0].Occurrences[0].Title; title != "Maintenance of ServiceA" {
+		t.Errorf("exp:%v, actual:%v", "Maintenance of ServiceA", title)
+	}
+
+	// occurrences in the past are not registered
+	req = httptest.NewRequest(http.MethodPost, "/plan?from=2020-01-01&day=3", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result reconcileResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response: %s", rec.Body.String())
+	}
+	if rec.Code != http.StatusOK || !result.DryRun || result.From != "2020-01-01" || result.To != "2020-01-03" {
+		t.Errorf("unexpected result: %v %s", rec.Code, rec.Body.String())
+	}
+	if len(repository.added) != 0 {
+		t.Errorf("nothing should be added: %v", repository.added)
+	}
+}
+
+func TestServeApply(t *testing.T) {
+	handler := newTestServeCommand(t, &fakeRepository{}).handler()
+	request := func(method string, path string) (int, reconcileResult) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var result reconcileResult
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return rec.Code, result
+	}
+
+	if code, _ := request(http.MethodGet, "/apply/status"); code != http.StatusNotFound {
+		t.Errorf("exp:%v, actual:%v", http.StatusNotFound, code)
+	}
+
+	// apply is executed in background
+	code, result := request(http.MethodPost, "/apply?from=2020-01-01&day=3")
+	if code != http.StatusAccepted || result.DryRun || result.Status != ApplyStatus_Running || result.From != "2020-01-01" {
+		t.Fatalf("unexpected result: %v %+v", code, result)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		code, result = request(http.MethodGet, "/apply/status")
+		if code != http.StatusOK {
+			t.Fatalf("exp:%v, actual:%v", http.StatusOK, code)
+		}
+		if result.Status == ApplyStatus_Done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("apply is not finished: %+v", result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if result.Error != "" || result.To != "2020-01-03" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
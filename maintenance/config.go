@@ -1,25 +1,127 @@
 package maintenance
 
 import (
+	"fmt"
 	"io/ioutil"
-	"log"
+	"os"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
 type StatuspageConfig struct {
+	// Owner of maintenances registered by this configuration.
+	// Maintenances of other owners are not deleted.
+	Owner string `yaml:"owner"`
+
+	// Policy when a recurring maintenance is overlapped with a maintenance created by other way.
+	// see ConflictPolicy_*
+	ConflictPolicy string `yaml:"conflictPolicy"`
+
 	StatuspagePageId   string              `yaml:"statuspagePageId"`
 	StatuspageServices []StatuspageService `yaml:"statuspageServices"`
+	StatuspagePages    []StatuspagePage    `yaml:"statuspagePages"`
+
+	// Default options of maintenances in all pages
+	MaintenanceDefaults MaintenanceOptions `yaml:"maintenanceDefaults"`
+
+	// Default locales and layout to compose localized title and body in all pages
+	Locales      []LocaleConfig `yaml:"locales"`
+	LocaleLayout LocaleLayout   `yaml:"localeLayout"`
+}
+
+// Configuration of a page of Statuspage
+type StatuspagePage struct {
+	Name               string              `yaml:"name"`
+	StatuspagePageId   string              `yaml:"statuspagePageId"`
+	ApiKeyEnv          string              `yaml:"apiKeyEnv"`
+	StatuspageServices []StatuspageService `yaml:"statuspageServices"`
+
+	// Default options of maintenances in the page
+	MaintenanceDefaults MaintenanceOptions `yaml:"maintenanceDefaults"`
+
+	// Locales and layout to compose localized title and body
+	Locales      []LocaleConfig `yaml:"locales"`
+	LocaleLayout LocaleLayout   `yaml:"localeLayout"`
+
+	// names of components keyed by componentId. This is taken from Statuspage.
+	componentNames map[string]string
 }
 
 type StatuspageService struct {
 	Service      string   `yaml:"service"`
+	Description  string   `yaml:"description"`
 	ComponentIds []string `yaml:"componentIds"`
+
+	// Names of components or component groups. A group is expanded to its components.
+	// "Group name/Component name" can be used to distinguish components which have the same name.
+	ComponentNames []string `yaml:"componentNames"`
+}
+
+// return pages to register maintenances.
+// `statuspagePageId` and `statuspageServices` at top level are treated as the first page.
+func (config StatuspageConfig) Pages() []StatuspagePage {
+	pages := make([]StatuspagePage, 0, len(config.StatuspagePages)+1)
+	if config.StatuspagePageId != "" {
+		pages = append(pages, StatuspagePage{
+			StatuspagePageId:   config.StatuspagePageId,
+			StatuspageServices: config.StatuspageServices,
+		})
+	}
+	pages = append(pages, config.StatuspagePages...)
+
+	for i := range pages {
+		pages[i].MaintenanceDefaults = pages[i].MaintenanceDefaults.merge(config.MaintenanceDefaults)
+		if len(pages[i].Locales) == 0 {
+			pages[i].Locales = config.Locales
+		}
+		pages[i].LocaleLayout = pages[i].LocaleLayout.merge(config.LocaleLayout).merge(defaultLocaleLayout())
+	}
+	return pages
 }
 
-func (config StatuspageConfig) findComponentByServiceName(service string) *StatuspageService {
+// return true if service is defined in any page
+func (config StatuspageConfig) hasService(service string) bool {
+	for _, p := range config.Pages() {
+		if p.findComponentByServiceName(service) != nil {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, c := range config.StatuspageServices {
+func (config StatuspageConfig) validate() error {
+	pages := config.Pages()
+	if len(pages) == 0 {
+		return configErrorf("statuspagePageId or statuspagePages is required")
+	}
+	for _, p := range pages {
+		if err := p.MaintenanceDefaults.validate(); err != nil {
+			return configErrorf("page %s: %w", p.label(), err)
+		}
+		for _, l := range p.Locales {
+			if err := l.validate(); err != nil {
+				return configErrorf("page %s: %w", p.label(), err)
+			}
+		}
+		if p.StatuspagePageId == "" {
+			return configErrorf("statuspagePageId is required for page: %s", p.Name)
+		}
+		if p.ApiKeyEnv != "" && os.Getenv(p.ApiKeyEnv) == "" {
+			return configErrorf("environment variable %s is not set for page: %s", p.ApiKeyEnv, p.label())
+		}
+		for _, s := range p.StatuspageServices {
+			if len(s.ComponentIds) == 0 && len(s.ComponentNames) == 0 {
+				return configErrorf("componentIds or componentNames is required for service: %s", s.Service)
+			}
+		}
+	}
+	return nil
+}
+
+func (page StatuspagePage) findComponentByServiceName(service string) *StatuspageService {
+
+	for _, c := range page.StatuspageServices {
 		if c.Service == service {
 			return &c
 		}
@@ -27,14 +129,150 @@ func (config StatuspageConfig) findComponentByServiceName(service string) *Statu
 	return nil
 }
 
-func loadFromFile(fileName string, data interface{}) {
+// resolve componentNames of services into componentIds
+func (page *StatuspagePage) resolveComponentNames(components []StatuspageComponnet) error {
+	page.componentNames = map[string]string{}
+	for _, c := range components {
+		page.componentNames[c.Id] = c.Name
+	}
+
+	for i := range page.StatuspageServices {
+		service := &page.StatuspageServices[i]
+		for _, name := range service.ComponentNames {
+			ids, err := resolveComponentName(name, components)
+			if err != nil {
+				return configErrorf("[%s] %w", service.Service, err)
+			}
+			service.ComponentIds = appendUniqueIds(service.ComponentIds, ids...)
+		}
+	}
+	return nil
+}
+
+// return ids of the component which has name.
+// If the component is a group, ids of components in the group are returned.
+func resolveComponentName(name string, components []StatuspageComponnet) ([]string, error) {
+	groupNames := map[string]string{}
+	for _, c := range components {
+		if c.Group {
+			groupNames[c.Id] = c.Name
+		}
+	}
+
+	found := make([]StatuspageComponnet, 0)
+	for _, c := range components {
+		qualifiedName := c.Name
+		if c.GroupId != "" {
+			qualifiedName = groupNames[c.GroupId] + "/" + c.Name
+		}
+		if c.Name == name || qualifiedName == name {
+			found = append(found, c)
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, fmt.Errorf("unknown component name: %s", name)
+	}
+	if len(found) > 1 {
+		ids := make([]string, 0, len(found))
+		for _, c := range found {
+			ids = append(ids, c.Id)
+		}
+		return nil, fmt.Errorf("ambiguous component name: %s matches %s", name, strings.Join(ids, ", "))
+	}
+
+	if !found[0].Group {
+		return []string{found[0].Id}, nil
+	}
+
+	ids := make([]string, 0)
+	for _, c := range components {
+		if c.GroupId == found[0].Id {
+			ids = append(ids, c.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("component group has no components: %s", name)
+	}
+	return ids, nil
+}
+
+// return names of components. componentId is returned if the name is unknown.
+func (page StatuspagePage) componentNamesOf(ids []string) []string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := page.componentNames[id]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, id)
+		}
+	}
+	return names
+}
+
+func appendUniqueIds(ids []string, newIds ...string) []string {
+	for _, newId := range newIds {
+		found := false
+		for _, id := range ids {
+			if id == newId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ids = append(ids, newId)
+		}
+	}
+	return ids
+}
+
+// return name of the first service which has any of components of the incident.
+// Empty string is returned if it's not found.
+func (page StatuspagePage) serviceOf(incident StatuspageIncident) string {
+	for _, s := range page.StatuspageServices {
+		if len(incident.intersectComponentIds(s.ComponentIds)) > 0 {
+			return s.Service
+		}
+	}
+	return ""
+}
+
+// return schedules of services which are defined in the page
+func (page StatuspagePage) filterSchedules(schedules []ScheduledTerm) []ScheduledTerm {
+	filtered := make([]ScheduledTerm, 0, len(schedules))
+	for _, s := range schedules {
+		if page.findComponentByServiceName(s.Service) != nil {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// return API key to access the page
+func (page StatuspagePage) accessToken(defaultAccessToken string) string {
+	if page.ApiKeyEnv != "" {
+		return os.Getenv(page.ApiKeyEnv)
+	}
+	return defaultAccessToken
+}
+
+// return name of the page to be shown in output
+func (page StatuspagePage) label() string {
+	if page.Name != "" {
+		return page.Name
+	}
+	return page.StatuspagePageId
+}
+
+func loadFromFile(fileName string, data interface{}) error {
 	buf, err := ioutil.ReadFile(fileName)
 	if err != nil {
-		log.Fatal(err)
+		return &ConfigError{Err: err}
 	}
 
 	err = yaml.Unmarshal(buf, data)
 	if err != nil {
-		log.Fatal(err)
+		return configErrorf("%s: %w", fileName, err)
 	}
+	return nil
 }
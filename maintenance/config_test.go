@@ -0,0 +1,209 @@
+package maintenance
+
+import (
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	patterns := []struct {
+		config StatuspageConfig // input
+		exp    []string         // expected pageIds
+	}{
+		// single page
+		{
+			StatuspageConfig{
+				StatuspagePageId: "page1",
+			},
+			[]string{"page1"},
+		},
+
+		// multiple pages
+		{
+			StatuspageConfig{
+				StatuspagePages: []StatuspagePage{
+					{StatuspagePageId: "page1"},
+					{StatuspagePageId: "page2"},
+				},
+			},
+			[]string{"page1", "page2"},
+		},
+
+		// both
+		{
+			StatuspageConfig{
+				StatuspagePageId: "page1",
+				StatuspagePages: []StatuspagePage{
+					{StatuspagePageId: "page2"},
+				},
+			},
+			[]string{"page1", "page2"},
+		},
+	}
+
+	for idx, row := range patterns {
+		pages := row.config.Pages()
+		if len(pages) != len(row.exp) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, pages)
+			continue
+		}
+		for i := range row.exp {
+			if pages[i].StatuspagePageId != row.exp[i] {
+				t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, pages)
+			}
+		}
+	}
+}
+
+func TestFilterSchedules(t *testing.T) {
+	page := StatuspagePage{
+		StatuspagePageId: "page1",
+		StatuspageServices: []StatuspageService{
+			{Service: "public", ComponentIds: []string{"c1"}},
+		},
+	}
+
+	schedules := []ScheduledTerm{
+		{Service: "public"},
+		{Service: "internal"},
+		{Service: "public"},
+	}
+
+	actual := page.filterSchedules(schedules)
+	if len(actual) != 2 {
+		t.Fatalf("exp:2, actual:%v", len(actual))
+	}
+	for _, s := range actual {
+		if s.Service != "public" {
+			t.Errorf("unexpected service: %v", s.Service)
+		}
+	}
+}
+
+func TestResolveComponentNames(t *testing.T) {
+	components := []StatuspageComponnet{
+		{Id: "g1", Name: "API", Group: true},
+		{Id: "c1", Name: "Search", GroupId: "g1"},
+		{Id: "c2", Name: "Upload", GroupId: "g1"},
+		{Id: "g2", Name: "Web", Group: true},
+		{Id: "c3", Name: "Search", GroupId: "g2"},
+		{Id: "c4", Name: "Login"},
+		{Id: "g3", Name: "Empty", Group: true},
+	}
+
+	patterns := []struct {
+		service StatuspageService // input
+		exp     []string          // expected componentIds
+		isErr   bool              // expected
+	}{
+		// component
+		{
+			StatuspageService{Service: "s", ComponentNames: []string{"Login"}},
+			[]string{"c4"},
+			false,
+		},
+
+		// component group
+		{
+			StatuspageService{Service: "s", ComponentNames: []string{"API"}},
+			[]string{"c1", "c2"},
+			false,
+		},
+
+		// qualified name
+		{
+			StatuspageService{Service: "s", ComponentNames: []string{"Web/Search"}},
+			[]string{"c3"},
+			false,
+		},
+
+		// merged with componentIds without duplication
+		{
+			StatuspageService{Service: "s", ComponentIds: []string{"c1"}, ComponentNames: []string{"API", "Login"}},
+			[]string{"c1", "c2", "c4"},
+			false,
+		},
+
+		// ambiguous name
+		{
+			StatuspageService{Service: "s", ComponentNames: []string{"Search"}},
+			nil,
+			true,
+		},
+
+		// unknown name
+		{
+			StatuspageService{Service: "s", ComponentNames: []string{"Unknown"}},
+			nil,
+			true,
+		},
+
+		// empty group
+		{
+			StatuspageService{Service: "s", ComponentNames: []string{"Empty"}},
+			nil,
+			true,
+		},
+	}
+
+	for idx, row := range patterns {
+		page := StatuspagePage{StatuspageServices: []StatuspageService{row.service}}
+		err := page.resolveComponentNames(components)
+
+		if row.isErr {
+			if err == nil {
+				t.Errorf("test(%v): error is expected", idx+1)
+			} else if ExitCode(err) != ExitCodeConfigError {
+				t.Errorf("test(%v): ConfigError is expected: %v", idx+1, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test(%v): unexpected error: %v", idx+1, err)
+			continue
+		}
+
+		actual := page.StatuspageServices[0].ComponentIds
+		if len(actual) != len(row.exp) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+			continue
+		}
+		for i := range row.exp {
+			if actual[i] != row.exp[i] {
+				t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+			}
+		}
+	}
+}
+
+func TestStatuspageConfigValidate(t *testing.T) {
+	service := StatuspageService{Service: "s", ComponentIds: []string{"c1"}}
+	invalidImpact := MaintenanceOptions{Impact: "unknown"}
+
+	patterns := []struct {
+		config StatuspageConfig // input
+		isErr  bool             // expected
+	}{
+		{StatuspageConfig{StatuspagePageId: "page1", StatuspageServices: []StatuspageService{service}}, false},
+		{StatuspageConfig{StatuspagePages: []StatuspagePage{{StatuspagePageId: "page1", StatuspageServices: []StatuspageService{service}}}}, false},
+
+		// no page
+		{StatuspageConfig{}, true},
+		// page without id
+		{StatuspageConfig{StatuspagePages: []StatuspagePage{{Name: "page1"}}}, true},
+		// service without components
+		{StatuspageConfig{StatuspagePageId: "page1", StatuspageServices: []StatuspageService{{Service: "s"}}}, true},
+		// invalid options
+		{StatuspageConfig{StatuspagePageId: "page1", MaintenanceDefaults: invalidImpact}, true},
+		// environment variable of API key is not set
+		{StatuspageConfig{StatuspagePages: []StatuspagePage{{StatuspagePageId: "page1", ApiKeyEnv: "MAINTENANCE_TEST_UNSET_API_KEY"}}}, true},
+	}
+
+	for idx, row := range patterns {
+		err := row.config.validate()
+		if row.isErr != (err != nil) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.isErr, err)
+		} else if err != nil && ExitCode(err) != ExitCodeConfigError {
+			t.Errorf("test(%v): ConfigError is expected: %v", idx+1, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Difference between a registered incident and a scheduled term
+type IncidentDiff struct {
+	MissingComponentIds []string // components which are scheduled but not in the incident
+	ExtraComponentIds   []string // components which are in the incident but not scheduled
+
+	OldStart time.Time
+	NewStart time.Time
+	OldEnd   time.Time
+	NewEnd   time.Time
+
+	OldTitle string
+	NewTitle string
+	OldBody  string
+	NewBody  string
+
+	bodyChanged bool                   // body is compared by bodyHash in metadata. see StatuspageIncident.hasBody
+	metadata    map[string]interface{} // metadata of this tool in the incident
+}
+
+// compare the incident with the scheduled term which has componentIds
+func diffIncident(incident StatuspageIncident, s ScheduledTerm, componentIds []string) IncidentDiff {
+	missing, extra := diffComponentIds(incident.componentIds(), componentIds)
+	return IncidentDiff{
+		MissingComponentIds: missing,
+		ExtraComponentIds:   extra,
+		OldStart:            incident.ScheduledFor,
+		NewStart:            s.Start,
+		OldEnd:              incident.ScheduledUntil,
+		NewEnd:              s.End,
+		OldTitle:            incident.Name,
+		NewTitle:            s.Title,
+		OldBody:             incident.body(),
+		NewBody:             s.Body,
+		bodyChanged:         !incident.hasBody(s.Body),
+		metadata:            incident.Metadata[key_toolNamespace],
+	}
+}
+
+// compare components as sets. Duplicated ids are ignored.
+//
+// e.g. actual:[a, b, b], expected:[b, c] -> missing:[c], extra:[a]
+func diffComponentIds(actual []string, expected []string) (missing []string, extra []string) {
+	missing = subtractIds(expected, actual)
+	extra = subtractIds(actual, expected)
+	return missing, extra
+}
+
+// return unique ids in ids1 which are not in ids2, keeping order of ids1
+func subtractIds(ids1 []string, ids2 []string) []string {
+	set := map[string]bool{}
+	for _, id := range ids2 {
+		set[id] = true
+	}
+
+	result := make([]string, 0)
+	for _, id := range ids1 {
+		if !set[id] {
+			result = append(result, id)
+			set[id] = true
+		}
+	}
+	return result
+}
+
+func (d IncidentDiff) hasComponentChanges() bool {
+	return len(d.MissingComponentIds) > 0 || len(d.ExtraComponentIds) > 0
+}
+
+func (d IncidentDiff) hasTermChanges() bool {
+	return !d.OldStart.Equal(d.NewStart) || !d.OldEnd.Equal(d.NewEnd)
+}
+
+func (d IncidentDiff) hasTitleChanges() bool {
+	return d.OldTitle != d.NewTitle
+}
+
+func (d IncidentDiff) hasBodyChanges() bool {
+	return d.bodyChanged
+}
+
+func (d IncidentDiff) IsEmpty() bool {
+	return !d.hasComponentChanges() && !d.hasTermChanges() && !d.hasTitleChanges() && !d.hasBodyChanges()
+}
+
+// return true if the incident should be deleted and registered again.
+// Changes of the term are not updated, so that subscribers are notified of the new term.
+func (d IncidentDiff) needsRecreate() bool {
+	return d.hasTermChanges()
+}
+
+// return request to update the incident by the diff
+func (d IncidentDiff) updateRequest(componentIds []string) StatuspageUpdateIncidentRequest {
+	update := StatuspageIncidentUpdate{}
+	if d.hasTitleChanges() {
+		update.Name = d.NewTitle
+	}
+	if d.hasBodyChanges() {
+		update.Body = d.NewBody
+
+		// bodyHash is updated with the body, keeping other metadata
+		metadata := map[string]interface{}{}
+		for k, v := range d.metadata {
+			metadata[k] = v
+		}
+		metadata[key_bodyHash] = bodyHashOf(d.NewBody)
+		update.Metadata = map[string]interface{}{key_toolNamespace: metadata}
+	}
+	if d.hasComponentChanges() {
+		update.ComponentIds = componentIds
+	}
+	return StatuspageUpdateIncidentRequest{Incident: update}
+}
+
+// return request to update the maintenance in progress by the diff.
+// Start can't be changed because the window has started, and false is returned if there is no other change.
+func (d IncidentDiff) inProgressUpdateRequest(componentIds []string) (StatuspageUpdateIncidentRequest, bool) {
+	request := d.updateRequest(componentIds)
+	if !d.OldEnd.Equal(d.NewEnd) {
+		end := d.NewEnd
+		request.Incident.ScheduledUntil = &end
+	}
+	return request, !d.OldEnd.Equal(d.NewEnd) || d.hasComponentChanges() || d.hasTitleChanges() || d.hasBodyChanges()
+}
+
+func (d IncidentDiff) String() string {
+	changes := make([]string, 0)
+	if d.hasTermChanges() {
+		changes = append(changes, fmt.Sprintf("term: %s - %s -> %s - %s", d.OldStart, d.OldEnd, d.NewStart, d.NewEnd))
+	}
+	if d.hasComponentChanges() {
+		changes = append(changes, fmt.Sprintf("components: +%v -%v", d.MissingComponentIds, d.ExtraComponentIds))
+	}
+	if d.hasTitleChanges() {
+		changes = append(changes, fmt.Sprintf("title: %q -> %q", d.OldTitle, d.NewTitle))
+	}
+	if d.hasBodyChanges() {
+		changes = append(changes, fmt.Sprintf("body: %q -> %q", d.OldBody, d.NewBody))
+	}
+	return strings.Join(changes, ", ")
+}
@@ -0,0 +1,157 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiffComponentIds(t *testing.T) {
+	patterns := []struct {
+		actual     []string // input
+		expected   []string // input
+		expMissing []string // expected
+		expExtra   []string // expected
+	}{
+		{[]string{"a", "b"}, []string{"b", "a"}, []string{}, []string{}},
+		{[]string{"a", "b", "b"}, []string{"b", "c"}, []string{"c"}, []string{"a"}},
+
+		// duplicated ids don't make a mismatch
+		{[]string{"a", "b"}, []string{"a", "a", "b"}, []string{}, []string{}},
+		{[]string{"a"}, []string{"a", "a"}, []string{}, []string{}},
+	}
+
+	for idx, row := range patterns {
+		missing, extra := diffComponentIds(row.actual, row.expected)
+		if fmt.Sprint(missing) != fmt.Sprint(row.expMissing) || fmt.Sprint(extra) != fmt.Sprint(row.expExtra) {
+			t.Errorf("test(%v): exp:%v %v, actual:%v %v", idx+1, row.expMissing, row.expExtra, missing, extra)
+		}
+	}
+}
+
+func TestIsSameComponentIdsWithDuplicates(t *testing.T) {
+	incident := StatuspageIncident{
+		Components: []StatuspageComponnet{{Id: "a"}, {Id: "b"}},
+	}
+	if !incident.isSameComponentIds([]string{"a", "b", "a"}) {
+		t.Errorf("duplicated ids should be ignored")
+	}
+	if incident.isSameComponentIds([]string{"a", "a"}) {
+		t.Errorf("missing component should be detected")
+	}
+}
+
+func TestDiffIncidents(t *testing.T) {
+	command := RecurringCommand{}
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1", "c2"}},
+		},
+	}
+	scheduleOf := func(key string) ScheduledTerm {
+		return ScheduledTerm{
+			Key:     key,
+			Service: "service1",
+			Title:   "new title",
+			Body:    "body",
+			Start:   timeOf(2020, 1, 1, 1, 0),
+			End:     timeOf(2020, 1, 1, 2, 0),
+		}
+	}
+
+	incidents := []StatuspageIncident{
+		// title and components are changed
+		{
+			Id:              "key1",
+			Name:            "old title",
+			Components:      []StatuspageComponnet{{Id: "c1"}},
+			IncidentUpdates: []StatuspageIncidentUpdateEntry{{Body: "body"}},
+			Metadata:        map[string]map[string]interface{}{key_toolNamespace: {key_scheduleKey: "key1"}},
+			ScheduledFor:    timeOf(2020, 1, 1, 1, 0),
+			ScheduledUntil:  timeOf(2020, 1, 1, 2, 0),
+		},
+		// term is changed
+		{
+			Id:              "key2",
+			Name:            "new title",
+			Components:      []StatuspageComponnet{{Id: "c1"}},
+			IncidentUpdates: []StatuspageIncidentUpdateEntry{{Body: "body"}},
+			Metadata:        map[string]map[string]interface{}{key_toolNamespace: {key_scheduleKey: "key2"}},
+			ScheduledFor:    timeOf(2020, 1, 1, 1, 0),
+			ScheduledUntil:  timeOf(2020, 1, 1, 3, 0),
+		},
+	}
+	schedules := []ScheduledTerm{scheduleOf("key1"), scheduleOf("key2")}
+
+	updates := command.diffIncidents(incidents, page, schedules)
+	if len(updates) != 1 {
+		t.Fatalf("exp:1, actual:%v", updates)
+	}
+	u := updates[0]
+	if u.Incident.Id != "key1" || u.Request.Incident.Name != "new title" || u.Request.Incident.Body != "" ||
+		fmt.Sprint(u.Request.Incident.ComponentIds) != "[c1 c2]" {
+		t.Errorf("unexpected update: %+v", u)
+	}
+
+	if !command.existsSameIncident(incidents, page, schedules[0]) {
+		t.Errorf("incident to be updated should exist")
+	}
+	if command.existsSameIncident(incidents, page, schedules[1]) {
+		t.Errorf("incident whose term is changed should be registered again")
+	}
+}
+
+func TestIncidentBody(t *testing.T) {
+	updatesOf := func(bodies ...string) []StatuspageIncidentUpdateEntry {
+		updates := make([]StatuspageIncidentUpdateEntry, 0)
+		for idx, body := range bodies {
+			updates = append(updates, StatuspageIncidentUpdateEntry{Body: body, CreatedAt: timeOf(2020, 1, 1, idx, 0)})
+		}
+		return updates
+	}
+	metadataOf := func(body string) map[string]map[string]interface{} {
+		if body == "" {
+			return map[string]map[string]interface{}{key_toolNamespace: {key_owner: "team1"}}
+		}
+		return map[string]map[string]interface{}{key_toolNamespace: {key_owner: "team1", key_bodyHash: bodyHashOf(body)}}
+	}
+
+	patterns := []struct {
+		incident StatuspageIncident // input
+		body     string             // input
+		expBody  string             // expected
+		expSame  bool               // expected
+	}{
+		// updates added by Statuspage are ignored
+		{StatuspageIncident{IncidentUpdates: updatesOf("body", "in progress", "completed"), Metadata: metadataOf("body")}, "body", "body", true},
+		{StatuspageIncident{IncidentUpdates: updatesOf("body", "in progress"), Metadata: metadataOf("body")}, "new body", "body", false},
+		// body updated by this tool
+		{StatuspageIncident{IncidentUpdates: updatesOf("body", "new body", "in progress"), Metadata: metadataOf("new body")}, "new body", "new body", true},
+		// update of the body was removed in console
+		{StatuspageIncident{IncidentUpdates: updatesOf("in progress"), Metadata: metadataOf("body")}, "body", "", false},
+		// incidents registered by older versions are compared with the first update
+		{StatuspageIncident{IncidentUpdates: updatesOf("body", "in progress"), Metadata: metadataOf("")}, "body", "body", true},
+		{StatuspageIncident{IncidentUpdates: updatesOf("body", "in progress"), Metadata: metadataOf("")}, "in progress", "body", false},
+	}
+
+	for idx, row := range patterns {
+		if body := row.incident.body(); body != row.expBody {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.expBody, body)
+		}
+		if same := row.incident.hasBody(row.body); same != row.expSame {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.expSame, same)
+		}
+	}
+
+	incident := StatuspageIncident{IncidentUpdates: updatesOf("body", "in progress"), Metadata: metadataOf("body")}
+	request := diffIncident(incident, ScheduledTerm{Body: "new body"}, []string{}).updateRequest([]string{})
+	metadata, _ := request.Incident.Metadata[key_toolNamespace].(map[string]interface{})
+	if request.Incident.Body != "new body" || metadata[key_bodyHash] != bodyHashOf("new body") || metadata[key_owner] != "team1" {
+		t.Errorf("unexpected request: %+v", request)
+	}
+	buf, _ := json.Marshal(request)
+	if !strings.Contains(string(buf), `"deliver_notifications":false`) {
+		t.Errorf("subscribers should not be notified of updates: %s", buf)
+	}
+}
@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes of this tool
+const (
+	ExitCodeOK             = 0
+	ExitCodeError          = 1
+	ExitCodeConfigError    = 2
+	ExitCodeAPIError       = 3
+	ExitCodePartialFailure = 4
+	ExitCodeDrift          = 5
+	ExitCodeLocked         = 6
+	ExitCodeStaleComponent = 7
+)
+
+// Error caused by command line arguments or configuration files
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("config error: %s", e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func configErrorf(format string, a ...interface{}) error {
+	return &ConfigError{Err: fmt.Errorf(format, a...)}
+}
+
+// Error returned from Statuspage API
+type APIError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("api error: %s %s: %s", e.Method, e.Url, e.Err)
+	}
+	return fmt.Sprintf("api error: %s %s: status %d: %s", e.Method, e.Url, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Error returned when some of operations are failed in continue-on-error mode
+type PartialFailureError struct {
+	Failed int
+	Total  int
+}
+
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("%d of %d operations failed", e.Failed, e.Total)
+}
+
+// Error returned when maintenances in Statuspage are different from the schedule
+type DriftError struct {
+	Count int
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("%d drifts are found", e.Count)
+}
+
+// Error returned when the lock is held by another run
+type LockError struct {
+	Name   string
+	Holder string
+}
+
+func (e *LockError) Error() string {
+	return fmt.Sprintf("lock %s is held by another run: %s", e.Name, e.Holder)
+}
+
+// Error returned when components in statuspage file are no longer found in Statuspage
+type StaleComponentError struct {
+	Count    int
+	Filename string
+}
+
+func (e *StaleComponentError) Error() string {
+	return fmt.Sprintf("%d componentIds or componentNames in %s are not found", e.Count, e.Filename)
+}
+
+// return exit code of the process for err
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+
+	var configErr *ConfigError
+	var partialErr *PartialFailureError
+	var apiErr *APIError
+	var driftErr *DriftError
+	var lockErr *LockError
+	var staleErr *StaleComponentError
+
+	switch {
+	case errors.As(err, &configErr):
+		return ExitCodeConfigError
+	case errors.As(err, &partialErr):
+		return ExitCodePartialFailure
+	case errors.As(err, &apiErr):
+		return ExitCodeAPIError
+	case errors.As(err, &driftErr):
+		return ExitCodeDrift
+	case errors.As(err, &lockErr):
+		return ExitCodeLocked
+	case errors.As(err, &staleErr):
+		return ExitCodeStaleComponent
+	default:
+		return ExitCodeError
+	}
+}
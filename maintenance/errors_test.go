@@ -0,0 +1,33 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	patterns := []struct {
+		err error // input
+		exp int   // expected
+	}{
+		{nil, ExitCodeOK},
+		{errors.New("unexpected"), ExitCodeError},
+		{configErrorf("invalid"), ExitCodeConfigError},
+		{&APIError{Method: "GET", Url: "/incidents", StatusCode: 500}, ExitCodeAPIError},
+		{&PartialFailureError{Failed: 1, Total: 2}, ExitCodePartialFailure},
+		{&DriftError{Count: 1}, ExitCodeDrift},
+		{&LockError{Name: "lock", Holder: "another"}, ExitCodeLocked},
+		{&StaleComponentError{Count: 1, Filename: "statuspage.yaml"}, ExitCodeStaleComponent},
+
+		// wrapped errors
+		{fmt.Errorf("FindAllScheduledIncidents err: %w", &APIError{Method: "GET", Url: "/incidents", StatusCode: 500}), ExitCodeAPIError},
+		{fmt.Errorf("page1: %w", configErrorf("invalid")), ExitCodeConfigError},
+	}
+
+	for idx, row := range patterns {
+		if actual := ExitCode(row.err); actual != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultTimeFormat = "2006-01-02 15:04 MST"
+
+// Text which is written as a string or a map keyed by locale in yaml
+//
+// e.g. title: "Maintenance"
+// e.g. title: {ja: "メンテナンス", en: "Maintenance"}
+type LocalizedText map[string]string
+
+// key of the text which is written as a string
+const noLocale = ""
+
+func (t *LocalizedText) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err == nil {
+		*t = LocalizedText{noLocale: text}
+		return nil
+	}
+
+	texts := map[string]string{}
+	if err := unmarshal(&texts); err != nil {
+		return err
+	}
+	*t = texts
+	return nil
+}
+
+// the text written as a string is also written as a string in JSON
+func (t LocalizedText) MarshalJSON() ([]byte, error) {
+	if text, ok := t[noLocale]; ok && len(t) == 1 {
+		return json.Marshal(text)
+	}
+	return json.Marshal(map[string]string(t))
+}
+
+// return true if the text is a map keyed by locale
+func (t LocalizedText) isLocalized() bool {
+	_, ok := t[noLocale]
+	return len(t) > 0 && !ok
+}
+
+// Configuration of a locale to compose title and body of maintenance
+type LocaleConfig struct {
+	Locale     string `yaml:"locale"`
+	Timezone   string `yaml:"timezone"`   // time zone to show the window of maintenance. Default is Asia/Tokyo
+	TimeFormat string `yaml:"timeFormat"` // layout of time.Format to show the window of maintenance
+}
+
+// Layout to compose texts of locales
+type LocaleLayout struct {
+	TitleSeparator string `yaml:"titleSeparator"`
+	BodySeparator  string `yaml:"bodySeparator"`
+}
+
+func defaultLocaleLayout() LocaleLayout {
+	return LocaleLayout{
+		TitleSeparator: " / ",
+		BodySeparator:  "\n\n",
+	}
+}
+
+func (l LocaleLayout) merge(defaults LocaleLayout) LocaleLayout {
+	if l.TitleSeparator == "" {
+		l.TitleSeparator = defaults.TitleSeparator
+	}
+	if l.BodySeparator == "" {
+		l.BodySeparator = defaults.BodySeparator
+	}
+	return l
+}
+
+func (l LocaleConfig) validate() error {
+	if l.Locale == "" {
+		return fmt.Errorf("locale is required")
+	}
+	if _, err := l.location(); err != nil {
+		return fmt.Errorf("invalid timezone of locale %s: %w", l.Locale, err)
+	}
+	return nil
+}
+
+func (l LocaleConfig) location() (*time.Location, error) {
+	if l.Timezone == "" {
+		return loc, nil
+	}
+	return time.LoadLocation(l.Timezone)
+}
+
+// return data whose local time is formatted for the locale
+func (l LocaleConfig) apply(data TemplateData) (TemplateData, error) {
+	location, err := l.location()
+	if err != nil {
+		return data, err
+	}
+	format := l.TimeFormat
+	if format == "" {
+		format = defaultTimeFormat
+	}
+
+	data.Locale = l.Locale
+	data.LocalStart = data.Start.In(location)
+	data.LocalEnd = data.End.In(location)
+	data.Window = fmt.Sprintf("%s - %s", data.LocalStart.Format(format), data.LocalEnd.Format(format))
+	return data, nil
+}
+
+// return locales to render the text.
+// locales which are not configured are rendered after configured locales, in order of name.
+func (t LocalizedText) locales(configs []LocaleConfig) []LocaleConfig {
+	if !t.isLocalized() {
+		return []LocaleConfig{{Locale: noLocale}}
+	}
+
+	result := make([]LocaleConfig, 0, len(t))
+	configured := map[string]bool{}
+	for _, c := range configs {
+		if _, ok := t[c.Locale]; ok {
+			result = append(result, c)
+		}
+		configured[c.Locale] = true
+	}
+
+	others := make([]string, 0)
+	for locale := range t {
+		if !configured[locale] {
+			others = append(others, locale)
+		}
+	}
+	sort.Strings(others)
+	for _, locale := range others {
+		result = append(result, LocaleConfig{Locale: locale})
+	}
+	return result
+}
+
+// render the text for each locale, and join them with separator
+func (t LocalizedText) render(name string, data TemplateData, configs []LocaleConfig, separator string) (string, error) {
+	texts := make([]string, 0, len(t))
+	for _, l := range t.locales(configs) {
+		localeData, err := l.apply(data)
+		if err != nil {
+			return "", err
+		}
+		text, err := renderTemplate(name, t[l.Locale], localeData)
+		if err != nil {
+			return "", err
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, separator), nil
+}
+
+// return error if the text of any locale can't be rendered
+func (t LocalizedText) validate(name string) error {
+	for locale, text := range t {
+		if err := validateTemplate(name, text); err != nil {
+			if locale != noLocale {
+				return fmt.Errorf("[%s] %w", locale, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
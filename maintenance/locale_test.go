@@ -0,0 +1,94 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestLocalizedTextUnmarshal(t *testing.T) {
+	var m RecurringMaintenance
+	err := yaml.Unmarshal([]byte(`
+service: ServiceA
+title: "Maintenance"
+body:
+  ja: "メンテナンス"
+  en: "Maintenance"
+`), &m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Title.isLocalized() || m.Title[noLocale] != "Maintenance" {
+		t.Errorf("unexpected title: %v", m.Title)
+	}
+	if !m.Body.isLocalized() || m.Body["ja"] != "メンテナンス" || m.Body["en"] != "Maintenance" {
+		t.Errorf("unexpected body: %v", m.Body)
+	}
+}
+
+func TestLocalizedTextRender(t *testing.T) {
+	configs := []LocaleConfig{
+		{Locale: "ja", Timezone: "Asia/Tokyo", TimeFormat: "1月2日 15:04"},
+		{Locale: "en", Timezone: "America/Los_Angeles", TimeFormat: "Jan 2 3:04PM MST"},
+	}
+	data := TemplateData{
+		Start: timeOf(2020, 1, 2, 10, 0),
+		End:   timeOf(2020, 1, 2, 11, 0),
+	}
+
+	patterns := []struct {
+		text LocalizedText // input
+		exp  string        // expected
+	}{
+		// locales are composed in order of configuration
+		{
+			LocalizedText{"en": "[{{.Locale}}] {{.Window}}", "ja": "[{{.Locale}}] {{.Window}}"},
+			"[ja] 1月2日 10:00 - 1月2日 11:00\n---\n[en] Jan 1 5:00PM PST - Jan 1 6:00PM PST",
+		},
+
+		// unconfigured locale is rendered at last
+		{
+			LocalizedText{"fr": "{{.Locale}}", "en": "{{.Locale}}"},
+			"en\n---\nfr",
+		},
+
+		// not localized
+		{
+			LocalizedText{noLocale: "{{.Window}}"},
+			"2020-01-02 10:00 JST - 2020-01-02 11:00 JST",
+		},
+	}
+
+	for idx, row := range patterns {
+		actual, err := row.text.render("body", data, configs, "\n---\n")
+		if err != nil {
+			t.Errorf("test(%v): unexpected error: %v", idx+1, err)
+			continue
+		}
+		if actual != row.exp {
+			t.Errorf("test(%v): exp:%q, actual:%q", idx+1, row.exp, actual)
+		}
+	}
+}
+
+func TestLocalizedTextMarshalJSON(t *testing.T) {
+	patterns := []struct {
+		text LocalizedText // input
+		exp  string        // expected
+	}{
+		{LocalizedText{noLocale: "Maintenance"}, `"Maintenance"`},
+		{LocalizedText{"ja": "メンテナンス", "en": "Maintenance"}, `{"en":"Maintenance","ja":"メンテナンス"}`},
+		{LocalizedText{}, `{}`},
+	}
+
+	for idx, row := range patterns {
+		actual, err := json.Marshal(row.text)
+		if err != nil {
+			t.Errorf("test(%v): unexpected error: %v", idx+1, err)
+		}
+		if string(actual) != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, string(actual))
+		}
+	}
+}
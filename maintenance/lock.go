@@ -0,0 +1,76 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// Lock to prevent concurrent runs from registering the same maintenances
+type Locker interface {
+	// acquire the lock. LockError is returned if it's held by another run.
+	Lock() error
+	Unlock() error
+}
+
+// error returned from flock when the file is locked by another process
+var errFileLocked = errors.New("file is locked")
+
+// Locker which holds an exclusive lock of the file.
+// The lock is released by OS when the process dies, so a lock is never left by a crashed run.
+// The file contains the process id of the holder, and is not removed on unlock.
+type FileLocker struct {
+	Filename string
+
+	file *os.File
+}
+
+func (l *FileLocker) Lock() error {
+	f, err := os.OpenFile(l.Filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock: %w", err)
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		if errors.Is(err, errFileLocked) {
+			return &LockError{Name: l.Filename, Holder: l.holder()}
+		}
+		return fmt.Errorf("failed to lock: %w", err)
+	}
+
+	holder := fmt.Sprintf("pid=%d run=%s time=%s\n", os.Getpid(), newRunId(), time.Now().Format(time.RFC3339))
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteAt([]byte(holder), 0)
+	}
+	if err != nil {
+		logger.Warn("failed to write holder of lock", "file", l.Filename, "err", err)
+	}
+	l.file = f
+	return nil
+}
+
+func (l *FileLocker) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	defer func() { l.file = nil }()
+
+	if err := funlock(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	return l.file.Close()
+}
+
+// return holder written in the lock file
+func (l *FileLocker) holder() string {
+	buf, err := ioutil.ReadFile(l.Filename)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(buf))
+}
@@ -0,0 +1,101 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileLocker(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "maintenance.lock")
+
+	first := &FileLocker{Filename: fileName}
+	second := &FileLocker{Filename: fileName}
+	if err := first.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	var lockErr *LockError
+	if err := second.Lock(); !errors.As(err, &lockErr) || ExitCode(err) != ExitCodeLocked {
+		t.Fatalf("lock held by another run should fail: %v", err)
+	}
+	if !strings.Contains(lockErr.Holder, fmt.Sprintf("pid=%d", os.Getpid())) {
+		t.Errorf("holder should be shown: %v", lockErr)
+	}
+	// releasing a lock which is not acquired doesn't release the lock of another run
+	if err := second.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Lock(); !errors.As(err, &lockErr) {
+		t.Fatalf("lock should remain: %v", err)
+	}
+
+	if err := first.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Lock(); err != nil {
+		t.Fatalf("released lock should be acquired: %v", err)
+	}
+
+	// lock is released when the file is closed, e.g. the process dies
+	second.file.Close()
+	second.file = nil
+	if err := first.Lock(); err != nil {
+		t.Fatalf("lock of closed file should be acquired: %v", err)
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeLocker struct {
+	locked   bool
+	acquired int
+	err      error
+}
+
+func (l *fakeLocker) Lock() error {
+	if l.err != nil {
+		return l.err
+	}
+	l.locked = true
+	l.acquired++
+	return nil
+}
+
+func (l *fakeLocker) Unlock() error {
+	l.locked = false
+	return nil
+}
+
+func TestRunWithLock(t *testing.T) {
+	locker := &fakeLocker{err: &LockError{Name: "test", Holder: "another"}}
+	command := RecurringCommand{
+		Locker: locker,
+		repositoryOf: func(page StatuspagePage) StatuspageRepository {
+			t.Fatal("incidents should not be read without lock")
+			return nil
+		},
+	}
+	if err := command.Run(); ExitCode(err) != ExitCodeLocked {
+		t.Errorf("exp:%v, actual:%v", ExitCodeLocked, err)
+	}
+
+	// lock is not used in dry run
+	command = RecurringCommand{Locker: locker, isDryRun: true, ScheduleFilename: filepath.Join(t.TempDir(), "missing.yaml")}
+	if err := command.Run(); ExitCode(err) == ExitCodeLocked {
+		t.Errorf("dry run should not be locked: %v", err)
+	}
+
+	locker = &fakeLocker{}
+	command = RecurringCommand{Locker: locker, ScheduleFilename: filepath.Join(t.TempDir(), "missing.yaml")}
+	if err := command.Run(); err == nil {
+		t.Errorf("missing schedule should fail")
+	}
+	if locker.acquired != 1 || locker.locked {
+		t.Errorf("lock should be acquired and released on failure: %+v", locker)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package maintenance
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// acquire exclusive lock of the file without blocking
+func flock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errFileLocked
+	}
+	return err
+}
+
+func funlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package maintenance
+
+import (
+	"errors"
+	"os"
+)
+
+func flock(f *os.File) error {
+	return errors.New("lock file is not supported on windows")
+}
+
+func funlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,180 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LogLevel int
+
+const (
+	LogLevel_Debug LogLevel = iota
+	LogLevel_Info
+	LogLevel_Warn
+	LogLevel_Error
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogLevel_Debug: "DEBUG",
+	LogLevel_Info:  "INFO",
+	LogLevel_Warn:  "WARN",
+	LogLevel_Error: "ERROR",
+}
+
+func (l LogLevel) String() string {
+	return logLevelNames[l]
+}
+
+// return level of the name. e.g. debug, info, warn, error
+func parseLogLevel(name string) (LogLevel, error) {
+	for level, n := range logLevelNames {
+		if strings.EqualFold(n, name) {
+			return level, nil
+		}
+	}
+	return LogLevel_Info, fmt.Errorf("unknown log level: %s", name)
+}
+
+const (
+	LogFormat_Text = "text"
+	LogFormat_Json = "json"
+)
+
+// Logger which writes a line of message and fields for each log.
+// Fields are given as pairs of key and value. e.g. logger.Info("add maintenance", "service", "ServiceA")
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  LogLevel
+	format string
+	now    func() time.Time
+}
+
+func NewLogger(w io.Writer, level LogLevel, format string) *Logger {
+	return &Logger{w: w, level: level, format: format, now: time.Now}
+}
+
+// logger used by all commands
+var logger = NewLogger(os.Stderr, LogLevel_Info, LogFormat_Text)
+
+// return logger used by all commands
+func DefaultLogger() *Logger {
+	return logger
+}
+
+func (l *Logger) Debug(msg string, fields ...interface{}) {
+	l.log(LogLevel_Debug, msg, fields)
+}
+
+func (l *Logger) Info(msg string, fields ...interface{}) {
+	l.log(LogLevel_Info, msg, fields)
+}
+
+func (l *Logger) Warn(msg string, fields ...interface{}) {
+	l.log(LogLevel_Warn, msg, fields)
+}
+
+func (l *Logger) Error(msg string, fields ...interface{}) {
+	l.log(LogLevel_Error, msg, fields)
+}
+
+func (l *Logger) log(level LogLevel, msg string, fields []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level {
+		return
+	}
+
+	keys := []string{"time", "level", "msg"}
+	values := []interface{}{l.now(), level.String(), msg}
+	for i := 0; i < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		var value interface{} = "!MISSING"
+		if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	if l.format == LogFormat_Json {
+		fmt.Fprintln(l.w, formatJsonLog(keys, values))
+	} else {
+		fmt.Fprintln(l.w, formatTextLog(keys, values))
+	}
+}
+
+// return log like `time=... level=INFO msg="add maintenance" service=ServiceA`
+func formatTextLog(keys []string, values []interface{}) string {
+	pairs := make([]string, 0, len(keys))
+	for i, key := range keys {
+		value := formatLogValue(values[i])
+		if value == "" || strings.ContainsAny(value, " =\"\n\t") {
+			value = fmt.Sprintf("%q", value)
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// return log like `{"time":"...","level":"INFO","msg":"add maintenance","service":"ServiceA"}`
+func formatJsonLog(keys []string, values []interface{}) string {
+	pairs := make([]string, 0, len(keys))
+	for i, key := range keys {
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(jsonLogValue(values[i]))
+		if err != nil {
+			v, _ = json.Marshal(formatLogValue(values[i]))
+		}
+		pairs = append(pairs, string(k)+":"+string(v))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatLogValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jsonLogValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// set level and format of the logger used by all commands
+func configureLogger(level string, format string) error {
+	l, err := parseLogLevel(level)
+	if err != nil {
+		return configErrorf("%s", err)
+	}
+	if format != LogFormat_Text && format != LogFormat_Json {
+		return configErrorf("unknown log format: %s", format)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.level = l
+	logger.format = format
+	return nil
+}
@@ -0,0 +1,104 @@
+package maintenance
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	now := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	patterns := []struct {
+		format string   // input
+		level  LogLevel // input
+		exp    string   // expected
+	}{
+		{
+			LogFormat_Text,
+			LogLevel_Info,
+			`time=2020-01-01T10:00:00Z level=INFO msg="delete maintenance" service=ServiceA incidentId=1 start=2020-01-01T10:00:00Z` + "\n" +
+				`time=2020-01-01T10:00:00Z level=ERROR msg=failed err="api error" dryRun=false` + "\n",
+		},
+		{
+			LogFormat_Json,
+			LogLevel_Info,
+			`{"time":"2020-01-01T10:00:00Z","level":"INFO","msg":"delete maintenance","service":"ServiceA","incidentId":"1","start":"2020-01-01T10:00:00Z"}` + "\n" +
+				`{"time":"2020-01-01T10:00:00Z","level":"ERROR","msg":"failed","err":"api error","dryRun":false}` + "\n",
+		},
+		{
+			LogFormat_Text,
+			LogLevel_Error,
+			`time=2020-01-01T10:00:00Z level=ERROR msg=failed err="api error" dryRun=false` + "\n",
+		},
+	}
+
+	for idx, row := range patterns {
+		var buf bytes.Buffer
+		l := NewLogger(&buf, row.level, row.format)
+		l.now = func() time.Time { return now }
+
+		l.Debug("skip maintenance", "service", "ServiceA")
+		l.Info("delete maintenance", "service", "ServiceA", "incidentId", "1", "start", now)
+		l.Error("failed", "err", errors.New("api error"), "dryRun", false)
+
+		if actual := buf.String(); actual != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+	}
+}
+
+func TestLogOperation(t *testing.T) {
+	page := StatuspagePage{
+		StatuspageServices: []StatuspageService{
+			{Service: "service1", ComponentIds: []string{"c1"}},
+		},
+	}
+	incident := recurringIncidentOf("1", "key1", "", timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 2, 0))
+
+	patterns := []struct {
+		isDryRun bool   // input
+		exp      string // expected
+	}{
+		{false, `level=INFO msg="delete maintenance" service=service1 name="" incidentId=1`},
+		{true, `level=INFO msg="[dryRun] delete maintenance" service=service1 name="" incidentId=1`},
+	}
+
+	original := logger
+	defer func() { logger = original }()
+	for idx, row := range patterns {
+		var buf bytes.Buffer
+		logger = NewLogger(&buf, LogLevel_Info, LogFormat_Text)
+
+		command := RecurringCommand{isDryRun: row.isDryRun}
+		if err := command.removeIncidents(&fakeRepository{}, page, []StatuspageIncident{incident}, Operation_Delete); err != nil {
+			t.Fatalf("test(%v): unexpected error: %v", idx+1, err)
+		}
+		if actual := buf.String(); !strings.Contains(actual, row.exp) {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	patterns := []struct {
+		name   string   // input
+		exp    LogLevel // expected
+		expErr bool     // expected
+	}{
+		{"debug", LogLevel_Debug, false},
+		{"INFO", LogLevel_Info, false},
+		{"warn", LogLevel_Warn, false},
+		{"error", LogLevel_Error, false},
+		{"verbose", LogLevel_Info, true},
+	}
+
+	for idx, row := range patterns {
+		actual, err := parseLogLevel(row.name)
+		if (err != nil) != row.expErr || actual != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v, err:%v", idx+1, row.exp, actual, err)
+		}
+	}
+}
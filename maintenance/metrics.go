@@ -0,0 +1,223 @@
+package maintenance
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics of this tool, exposed in Prometheus text format by /metrics of daemon and serve commands
+var metrics = newToolMetrics()
+
+type toolMetrics struct {
+	registry          *metricsRegistry
+	incidents         *metricVec
+	operationFailures *metricVec
+	runs              *metricVec
+	lastSuccess       *metricVec
+	scheduledWindows  *metricVec
+	apiRequests       *metricVec
+}
+
+func newToolMetrics() *toolMetrics {
+	r := newMetricsRegistry()
+	return &toolMetrics{
+		registry: r,
+		incidents: r.counter(
+			"statuspage_maintenance_incidents_total",
+			"Count of maintenances which are added, deleted, updated, skipped or conflicted.",
+			"page", "service", "operation"),
+		operationFailures: r.counter(
+			"statuspage_maintenance_operation_failures_total",
+			"Count of failed operations to Statuspage.",
+			"page", "operation"),
+		runs: r.counter(
+			"statuspage_maintenance_runs_total",
+			"Count of runs to register maintenances.",
+			"result"),
+		lastSuccess: r.gauge(
+			"statuspage_maintenance_last_success_timestamp_seconds",
+			"Unix time of the last successful run."),
+		scheduledWindows: r.gauge(
+			"statuspage_maintenance_scheduled_windows",
+			"Count of future maintenances registered in the horizon of the last run.",
+			"page", "service"),
+		apiRequests: r.histogram(
+			"statuspage_maintenance_api_request_duration_seconds",
+			"Latency of requests to Statuspage API.",
+			[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			"method", "endpoint", "status"),
+	}
+}
+
+func (m *toolMetrics) handler() http.Handler {
+	return m.registry.handler()
+}
+
+const (
+	metricKind_Counter   = "counter"
+	metricKind_Gauge     = "gauge"
+	metricKind_Histogram = "histogram"
+)
+
+type metricsRegistry struct {
+	mu      sync.Mutex
+	metrics []*metricVec
+}
+
+// Metric which has a value for each combination of labels
+type metricVec struct {
+	registry *metricsRegistry
+	name     string
+	help     string
+	kind     string
+	labels   []string
+	buckets  []float64 // upper bounds of histogram
+	values   map[string]*metricValue
+}
+
+type metricValue struct {
+	labelValues []string
+	value       float64  // value of counter and gauge, or sum of histogram
+	counts      []uint64 // counts of each bucket of histogram
+	count       uint64
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{}
+}
+
+func (r *metricsRegistry) counter(name string, help string, labels ...string) *metricVec {
+	return r.register(&metricVec{name: name, help: help, kind: metricKind_Counter, labels: labels})
+}
+
+func (r *metricsRegistry) gauge(name string, help string, labels ...string) *metricVec {
+	return r.register(&metricVec{name: name, help: help, kind: metricKind_Gauge, labels: labels})
+}
+
+func (r *metricsRegistry) histogram(name string, help string, buckets []float64, labels ...string) *metricVec {
+	return r.register(&metricVec{name: name, help: help, kind: metricKind_Histogram, labels: labels, buckets: buckets})
+}
+
+func (r *metricsRegistry) register(m *metricVec) *metricVec {
+	m.registry = r
+	m.values = map[string]*metricValue{}
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// return value of the labels. It must be called with lock of the registry.
+func (m *metricVec) valueOf(labelValues []string) *metricValue {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s requires %d labels: %v", m.name, len(m.labels), labelValues))
+	}
+	key := strings.Join(labelValues, "\x00")
+	v, ok := m.values[key]
+	if !ok {
+		v = &metricValue{labelValues: labelValues, counts: make([]uint64, len(m.buckets))}
+		m.values[key] = v
+	}
+	return v
+}
+
+// return current value of the labels
+func (m *metricVec) get(labelValues ...string) float64 {
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+	return m.valueOf(labelValues).value
+}
+
+func (m *metricVec) add(delta float64, labelValues ...string) {
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+	m.valueOf(labelValues).value += delta
+}
+
+func (m *metricVec) set(value float64, labelValues ...string) {
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+	m.valueOf(labelValues).value = value
+}
+
+func (m *metricVec) observe(value float64, labelValues ...string) {
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+	v := m.valueOf(labelValues)
+	for i, upper := range m.buckets {
+		if value <= upper {
+			v.counts[i]++
+		}
+	}
+	v.value += value
+	v.count++
+}
+
+// write all metrics in Prometheus text format
+func (r *metricsRegistry) write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+
+		keys := make([]string, 0, len(m.values))
+		for key := range m.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			v := m.values[key]
+			if m.kind != metricKind_Histogram {
+				fmt.Fprintf(w, "%s%s %s\n", m.name, formatLabels(m.labels, v.labelValues), formatMetricValue(v.value))
+				continue
+			}
+
+			labels := append(append([]string{}, m.labels...), "le")
+			for i, upper := range m.buckets {
+				values := append(append([]string{}, v.labelValues...), formatMetricValue(upper))
+				fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(labels, values), v.counts[i])
+			}
+			values := append(append([]string{}, v.labelValues...), "+Inf")
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(labels, values), v.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", m.name, formatLabels(m.labels, v.labelValues), formatMetricValue(v.value))
+			fmt.Fprintf(w, "%s_count%s %d\n", m.name, formatLabels(m.labels, v.labelValues), v.count)
+		}
+	}
+}
+
+// return labels like {name="value",...}. Empty string is returned if there is no label.
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(names))
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// serve metrics in Prometheus text format
+func (r *metricsRegistry) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.write(w)
+	})
+}
+
+// record latency and status of a request to Statuspage API.
+// endpoint is the path without ids like "incidents/{id}", so that the number of labels is bounded.
+func observeAPIRequest(method string, endpoint string, status string, start time.Time) {
+	metrics.apiRequests.observe(time.Since(start).Seconds(), method, endpoint, status)
+}
@@ -0,0 +1,108 @@
+package maintenance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsRegistryWrite(t *testing.T) {
+	registry := newMetricsRegistry()
+	counter := registry.counter("test_total", "Count of test.", "page", "operation")
+	gauge := registry.gauge("test_timestamp", "Time of test.")
+	histogram := registry.histogram("test_seconds", "Latency of test.", []float64{0.1, 1}, "method")
+
+	counter.add(1, "page2", "add")
+	counter.add(1, "page1", "add")
+	counter.add(2, "page1", "add")
+	gauge.set(1577804400)
+	histogram.observe(0.05, "GET")
+	histogram.observe(0.5, "GET")
+	histogram.observe(2, "GET")
+
+	var buf bytes.Buffer
+	registry.write(&buf)
+
+	exp := strings.Join([]string{
+		"# HELP test_total Count of test.",
+		"# TYPE test_total counter",
+		`test_total{page="page1",operation="add"} 3`,
+		`test_total{page="page2",operation="add"} 1`,
+		"# HELP test_timestamp Time of test.",
+		"# TYPE test_timestamp gauge",
+		"test_timestamp 1.5778044e+09",
+		"# HELP test_seconds Latency of test.",
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{method="GET",le="0.1"} 1`,
+		`test_seconds_bucket{method="GET",le="1"} 2`,
+		`test_seconds_bucket{method="GET",le="+Inf"} 3`,
+		`test_seconds_sum{method="GET"} 2.55`,
+		`test_seconds_count{method="GET"} 3`,
+		"",
+	}, "\n")
+	if actual := buf.String(); actual != exp {
+		t.Errorf("exp:\n%v\nactual:\n%v", exp, actual)
+	}
+}
+
+// replace metrics with fresh ones during the test
+func newTestMetrics(t *testing.T) *toolMetrics {
+	original := metrics
+	metrics = newToolMetrics()
+	t.Cleanup(func() { metrics = original })
+	return metrics
+}
+
+func TestRecordMetrics(t *testing.T) {
+	patterns := []struct {
+		isDryRun bool    // input
+		exp      float64 // expected
+	}{
+		{false, 1},
+		{true, 0},
+	}
+
+	for idx, row := range patterns {
+		m := newTestMetrics(t)
+		command := RecurringCommand{isDryRun: row.isDryRun}
+		command.record(OperationResult{Page: "page1", Service: "service1", Operation: Operation_Add})
+
+		if actual := m.incidents.get("page1", "service1", Operation_Add); actual != row.exp {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.exp, actual)
+		}
+	}
+}
+
+func TestScheduledWindows(t *testing.T) {
+	m := newTestMetrics(t)
+	tomorrow := dateIn(time.Now()).AddDate(0, 0, 1)
+	page := StatuspagePage{StatuspageServices: []StatuspageService{
+		{Service: "ServiceA", ComponentIds: []string{"c1"}},
+		{Service: "ServiceB", ComponentIds: []string{"c2"}},
+	}}
+	schedules := []ScheduledTerm{
+		{Key: "key1", Service: "ServiceA", Start: tomorrow.Add(10 * time.Hour), End: tomorrow.Add(11 * time.Hour), ComponentIds: []string{"c1"}},
+		{Key: "key2", Service: "ServiceA", Start: tomorrow.Add(12 * time.Hour), End: tomorrow.Add(13 * time.Hour), ComponentIds: []string{"c1"}},
+	}
+	incidents := []StatuspageIncident{{
+		Id:             "1",
+		Components:     []StatuspageComponnet{{Id: "c1"}},
+		Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleKey: "key1"}},
+		ScheduledFor:   schedules[0].Start,
+		ScheduledUntil: schedules[0].End,
+	}}
+
+	// key2 fails to be registered
+	command := RecurringCommand{FromDate: tomorrow, ToDate: tomorrow}
+	if err := command.registerIncidents(&failingAddRepository{}, incidents, page, schedules); err == nil {
+		t.Fatal("error is expected")
+	}
+
+	if actual := m.scheduledWindows.get("", "ServiceA"); actual != 1 {
+		t.Errorf("exp:%v, actual:%v", 1, actual)
+	}
+	if actual := m.scheduledWindows.get("", "ServiceB"); actual != 0 {
+		t.Errorf("exp:%v, actual:%v", 0, actual)
+	}
+}
@@ -0,0 +1,143 @@
+package maintenance
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	Operation_List   = "list"
+	Operation_Add    = "add"
+	Operation_Delete = "delete"
+	Operation_Update = "update"
+
+	// delete of a maintenance registered twice for the same schedule
+	Operation_DeleteDuplicate = "delete-duplicate"
+
+	// not operations to Statuspage, but counted in metrics
+	Operation_Skip     = "skip"
+	Operation_Conflict = "conflict"
+)
+
+// Result of an operation to Statuspage
+type OperationResult struct {
+	Page       string
+	Service    string
+	Operation  string
+	Name       string
+	IncidentId string
+	Start      time.Time
+	End        time.Time
+	Err        error
+}
+
+func (r OperationResult) isSucceeded() bool {
+	return r.Err == nil
+}
+
+// Results of operations executed by a command
+type Report struct {
+	Results []OperationResult
+}
+
+func (r *Report) add(result OperationResult) {
+	r.Results = append(r.Results, result)
+}
+
+func (r *Report) failedCount() int {
+	count := 0
+	for _, result := range r.Results {
+		if !result.isSucceeded() {
+			count++
+		}
+	}
+	return count
+}
+
+// return names of reconciled pages and pages of results in order of appearance
+func (r *Report) pages(reconciled []string) []string {
+	pages := make([]string, 0)
+	found := map[string]bool{}
+	for _, page := range reconciled {
+		if !found[page] {
+			found[page] = true
+			pages = append(pages, page)
+		}
+	}
+	for _, result := range r.Results {
+		if !found[result.Page] {
+			found[result.Page] = true
+			pages = append(pages, result.Page)
+		}
+	}
+	return pages
+}
+
+// return count of succeeded and failed operations of the page
+func (r *Report) countOf(page string) (succeeded int, failed int) {
+	for _, result := range r.Results {
+		if result.Page != page {
+			continue
+		}
+		if result.isSucceeded() {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	return succeeded, failed
+}
+
+// return PartialFailureError if some of operations are failed
+func (r *Report) err() error {
+	if failed := r.failedCount(); failed > 0 {
+		return &PartialFailureError{Failed: failed, Total: len(r.Results)}
+	}
+	return nil
+}
+
+// print results of operations as a table, and the summary of reconciled pages
+func (r *Report) Print(w io.Writer, reconciled []string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PAGE\tOPERATION\tRESULT\tNAME\tSTART\tEND\tINCIDENT\tERROR")
+	for _, result := range r.Results {
+		status := "succeeded"
+		errMessage := ""
+		if !result.isSucceeded() {
+			status = "failed"
+			errMessage = result.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.Page,
+			result.Operation,
+			status,
+			result.Name,
+			formatReportTime(result.Start),
+			formatReportTime(result.End),
+			result.IncidentId,
+			errMessage,
+		)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	r.PrintSummary(w, reconciled)
+}
+
+// print count of succeeded and failed operations of each page.
+// Reconciled pages are printed even if no operation is executed.
+func (r *Report) PrintSummary(w io.Writer, reconciled []string) {
+	for _, page := range r.pages(reconciled) {
+		succeeded, failed := r.countOf(page)
+		fmt.Fprintf(w, "%s: %d succeeded, %d failed\n", page, succeeded, failed)
+	}
+}
+
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	failed := errors.New("failed")
+
+	patterns := []struct {
+		results    []OperationResult // input
+		reconciled []string          // input
+		expFailed  int               // expected
+		expSummary string            // expected
+	}{
+		{[]OperationResult{}, []string{}, 0, ""},
+		{
+			[]OperationResult{
+				{Page: "page1", Operation: Operation_Add},
+				{Page: "page1", Operation: Operation_Delete, Err: failed},
+				{Page: "page2", Operation: Operation_Add},
+			},
+			[]string{"page1", "page2"},
+			1,
+			"page1: 1 succeeded, 1 failed\npage2: 1 succeeded, 0 failed\n",
+		},
+		// pages without operations are also printed
+		{
+			[]OperationResult{
+				{Page: "page2", Operation: Operation_Add},
+			},
+			[]string{"page1", "page2", "page3"},
+			0,
+			"page1: 0 succeeded, 0 failed\npage2: 1 succeeded, 0 failed\npage3: 0 succeeded, 0 failed\n",
+		},
+	}
+
+	for idx, row := range patterns {
+		report := Report{Results: row.results}
+		if actual := report.failedCount(); actual != row.expFailed {
+			t.Errorf("test(%v): exp:%v, actual:%v", idx+1, row.expFailed, actual)
+		}
+
+		err := report.err()
+		var partialErr *PartialFailureError
+		if (row.expFailed > 0) != errors.As(err, &partialErr) {
+			t.Errorf("test(%v): unexpected error: %v", idx+1, err)
+		} else if partialErr != nil && (partialErr.Failed != row.expFailed || partialErr.Total != len(row.results)) {
+			t.Errorf("test(%v): unexpected error: %v", idx+1, partialErr)
+		}
+
+		var summary bytes.Buffer
+		report.PrintSummary(&summary, row.reconciled)
+		if summary.String() != row.expSummary {
+			t.Errorf("test(%v): exp:%q, actual:%q", idx+1, row.expSummary, summary.String())
+		}
+
+		var table bytes.Buffer
+		report.Print(&table, row.reconciled)
+		if lines := strings.Split(table.String(), "\n"); !strings.HasPrefix(lines[0], "PAGE") || len(lines) < len(row.results)+1 {
+			t.Errorf("test(%v): unexpected table: %s", idx+1, table.String())
+		}
+		if row.expFailed > 0 && !strings.Contains(table.String(), "failed") {
+			t.Errorf("test(%v): failed operations should be shown: %s", idx+1, table.String())
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Local state of maintenances created by this tool
+type State struct {
+	Incidents []StateIncident `json:"incidents"`
+}
+
+// Maintenance created by this tool, as it was registered or updated last time
+type StateIncident struct {
+	PageId         string    `json:"pageId"`
+	IncidentId     string    `json:"incidentId"`
+	ScheduleKey    string    `json:"scheduleKey"`
+	Name           string    `json:"name"`
+	ScheduledFor   time.Time `json:"scheduledFor"`
+	ScheduledUntil time.Time `json:"scheduledUntil"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// load state from the file. Empty state is returned if the file doesn't exist.
+func loadState(fileName string) (*State, error) {
+	state := &State{Incidents: make([]StateIncident, 0)}
+	buf, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, configErrorf("failed to read state: %s", err)
+	}
+	if err := json.Unmarshal(buf, state); err != nil {
+		return nil, configErrorf("invalid state %s: %s", fileName, err)
+	}
+	return state, nil
+}
+
+// write state to the file. The file is replaced at once, so that it's not broken on failure.
+func (s *State) save(fileName string) error {
+	sort.SliceStable(s.Incidents, func(i, j int) bool {
+		if s.Incidents[i].PageId != s.Incidents[j].PageId {
+			return s.Incidents[i].PageId < s.Incidents[j].PageId
+		}
+		return s.Incidents[i].ScheduledFor.Before(s.Incidents[j].ScheduledFor)
+	})
+
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(buf, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fileName); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// return incidents of the page. nil state has no incident.
+func (s *State) incidentsOf(pageId string) []StateIncident {
+	result := make([]StateIncident, 0)
+	if s == nil {
+		return result
+	}
+	for _, i := range s.Incidents {
+		if i.PageId == pageId {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+func (s *State) find(pageId string, incidentId string) *StateIncident {
+	if s == nil {
+		return nil
+	}
+	for idx, i := range s.Incidents {
+		if i.PageId == pageId && i.IncidentId == incidentId {
+			return &s.Incidents[idx]
+		}
+	}
+	return nil
+}
+
+// add or replace the incident. Nothing is done for nil state or incident without id.
+func (s *State) put(incident StateIncident) {
+	if s == nil || incident.IncidentId == "" {
+		return
+	}
+	incident.UpdatedAt = time.Now()
+	if i := s.find(incident.PageId, incident.IncidentId); i != nil {
+		*i = incident
+		return
+	}
+	s.Incidents = append(s.Incidents, incident)
+}
+
+func (s *State) remove(pageId string, incidentId string) {
+	if s == nil {
+		return
+	}
+	incidents := make([]StateIncident, 0, len(s.Incidents))
+	for _, i := range s.Incidents {
+		if i.PageId != pageId || i.IncidentId != incidentId {
+			incidents = append(incidents, i)
+		}
+	}
+	s.Incidents = incidents
+}
+
+// return state of the incident registered in the page
+func stateIncidentOf(pageId string, incident StatuspageIncident) StateIncident {
+	return StateIncident{
+		PageId:         pageId,
+		IncidentId:     incident.Id,
+		ScheduleKey:    incident.scheduleKey(),
+		Name:           incident.Name,
+		ScheduledFor:   incident.ScheduledFor,
+		ScheduledUntil: incident.ScheduledUntil,
+	}
+}
+
+// return state updated by the request
+func (i StateIncident) apply(request StatuspageUpdateIncidentRequest) StateIncident {
+	if request.Incident.Name != "" {
+		i.Name = request.Incident.Name
+	}
+	if request.Incident.ScheduledFor != nil {
+		i.ScheduledFor = *request.Incident.ScheduledFor
+	}
+	if request.Incident.ScheduledUntil != nil {
+		i.ScheduledUntil = *request.Incident.ScheduledUntil
+	}
+	return i
+}
+
+// return true if the incident is not changed from the state
+func (i StateIncident) isSame(incident StatuspageIncident) bool {
+	return i.Name == incident.Name &&
+		i.ScheduledFor.Equal(incident.ScheduledFor) &&
+		i.ScheduledUntil.Equal(incident.ScheduledUntil)
+}
@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateSaveAndLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := loadState(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Incidents) != 0 {
+		t.Fatalf("state should be empty: %v", state)
+	}
+
+	state.put(StateIncident{PageId: "page1", IncidentId: "2", ScheduledFor: timeOf(2020, 1, 2, 1, 0)})
+	state.put(StateIncident{PageId: "page1", IncidentId: "1", ScheduledFor: timeOf(2020, 1, 1, 1, 0)})
+	state.put(StateIncident{PageId: "page1", IncidentId: "1", ScheduledFor: timeOf(2020, 1, 1, 1, 0), Name: "updated"})
+	state.put(StateIncident{PageId: "page1", IncidentId: ""})
+	if err := state.save(fileName); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadState(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Incidents) != 2 || loaded.Incidents[0].IncidentId != "1" || loaded.Incidents[0].Name != "updated" {
+		t.Errorf("unexpected state: %v", loaded.Incidents)
+	}
+
+	loaded.remove("page1", "1")
+	if loaded.find("page1", "1") != nil || loaded.find("page1", "2") == nil {
+		t.Errorf("unexpected state: %v", loaded.Incidents)
+	}
+}
+
+func TestSyncState(t *testing.T) {
+	page := StatuspagePage{StatuspagePageId: "page1"}
+	stateOf := func(id string, name string) StateIncident {
+		incident := recurringIncidentOf(id, "key"+id, "", timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 2, 0))
+		incident.Name = name
+		return stateIncidentOf("page1", incident)
+	}
+
+	command := RecurringCommand{
+		state: &State{Incidents: []StateIncident{
+			stateOf("listed", "old name"),
+			stateOf("unlisted", "name"),
+			stateOf("deleted", "name"),
+			stateOf("completed", "name"),
+		}},
+	}
+	repository := &fakeRepository{
+		others: []StatuspageIncident{
+			{
+				Id:             "unlisted",
+				Name:           "name",
+				Status:         IncidentStatus_Scheduled,
+				Components:     []StatuspageComponnet{{Id: "c1"}},
+				Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "keyunlisted"}},
+				ScheduledFor:   timeOf(2020, 1, 1, 1, 0),
+				ScheduledUntil: timeOf(2020, 1, 1, 2, 0),
+			},
+			{
+				Id:             "completed",
+				Name:           "name",
+				Status:         IncidentStatus_Completed,
+				Components:     []StatuspageComponnet{{Id: "c1"}},
+				Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "keycompleted"}},
+				ScheduledFor:   timeOf(2020, 1, 1, 1, 0),
+				ScheduledUntil: timeOf(2020, 1, 1, 2, 0),
+			},
+		},
+	}
+	incidents := []StatuspageIncident{
+		{
+			Id:             "listed",
+			Name:           "new name",
+			Status:         IncidentStatus_Scheduled,
+			Components:     []StatuspageComponnet{{Id: "c1"}},
+			Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "keylisted"}},
+			ScheduledFor:   timeOf(2020, 1, 1, 1, 0),
+			ScheduledUntil: timeOf(2020, 1, 1, 2, 0),
+		},
+		{
+			Id:             "new",
+			Name:           "name",
+			Status:         IncidentStatus_Scheduled,
+			Components:     []StatuspageComponnet{{Id: "c1"}},
+			Metadata:       map[string]map[string]interface{}{key_toolNamespace: {key_scheduleType: ScheduleType_Recurring, key_scheduleKey: "keynew"}},
+			ScheduledFor:   timeOf(2020, 1, 1, 1, 0),
+			ScheduledUntil: timeOf(2020, 1, 1, 2, 0),
+		},
+	}
+
+	actual, err := command.syncState(repository, page, incidents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, 0)
+	for _, i := range actual {
+		ids = append(ids, i.Id)
+	}
+	if exp := []string{"listed", "new", "unlisted"}; fmt.Sprint(ids) != fmt.Sprint(exp) {
+		t.Errorf("exp:%v, actual:%v", exp, ids)
+	}
+
+	patterns := []struct {
+		id      string // input
+		exists  bool   // expected
+		expName string // expected
+	}{
+		{"listed", true, "new name"},
+		{"new", true, "name"},
+		{"unlisted", true, "name"},
+		{"deleted", false, ""},
+		{"completed", false, ""},
+	}
+	for idx, row := range patterns {
+		s := command.state.find("page1", row.id)
+		if (s != nil) != row.exists || (s != nil && s.Name != row.expName) {
+			t.Errorf("test(%v): exp:%v %v, actual:%v", idx+1, row.exists, row.expName, s)
+		}
+	}
+}
@@ -2,18 +2,35 @@ package maintenance
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const key_toolNamespace = "statuspage_register_tool"
 const key_scheduleType = "scheduleType"
 const key_scheduleKey = "scheduleKey"
+const key_owner = "owner"
+const key_bodyHash = "bodyHash"
 const ScheduleType_Recurring = "recurring"
+const ScheduleType_Oneoff = "oneoff" // registered by once command, and never deleted by recurring command
+
+// Status of scheduled maintenance
+const (
+	IncidentStatus_Scheduled  = "scheduled"
+	IncidentStatus_InProgress = "in_progress"
+	IncidentStatus_Verifying  = "verifying"
+	IncidentStatus_Completed  = "completed"
+)
 
 // Request body of creating Incident
 type StatuspageCreateIncidentRequest struct {
@@ -48,6 +65,23 @@ type StatuspageIncidentRequest struct {
 	ScheduledAutoTransition                   bool                   `json:"scheduled_auto_transition"`
 }
 
+// Request body of updating Incident
+type StatuspageUpdateIncidentRequest struct {
+	Incident StatuspageIncidentUpdate `json:"incident"`
+}
+
+// Incident Data of updating Incident. Empty values are not updated.
+// Subscribers are not notified of updates.
+type StatuspageIncidentUpdate struct {
+	Name                 string                 `json:"name,omitempty"`
+	Body                 string                 `json:"body,omitempty"`
+	ScheduledFor         *time.Time             `json:"scheduled_for,omitempty"`
+	ScheduledUntil       *time.Time             `json:"scheduled_until,omitempty"`
+	ComponentIds         []string               `json:"component_ids,omitempty"`
+	Metadata             map[string]interface{} `json:"metadata,omitempty"`
+	DeliverNotifications bool                   `json:"deliver_notifications"`
+}
+
 // Incident Data of Statuspage
 type StatuspageIncident struct {
 	Id                      string                            `json:"id"`
@@ -55,7 +89,7 @@ type StatuspageIncident struct {
 	CreatedAt               time.Time                         `json:"created_at"`
 	Impact                  string                            `json:"impact"`
 	ImpactOverride          string                            `json:"impact_override"`
-	IncidentUpdates         []interface{}                     `json:"incident_updates"`
+	IncidentUpdates         []StatuspageIncidentUpdateEntry   `json:"incident_updates"`
 	Metadata                map[string]map[string]interface{} `json:"metadata"`
 	MonitoringAt            time.Time                         `json:"monitoring_at"`
 	Name                    string                            `json:"name"`
@@ -72,6 +106,75 @@ type StatuspageIncident struct {
 	UpdatedAt               time.Time                         `json:"updated_at"`
 }
 
+// Update of Incident. Body of the incident is written in updates.
+type StatuspageIncidentUpdateEntry struct {
+	Id        string    `json:"id"`
+	Status    string    `json:"status"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// return body written by this tool.
+// It's the latest update whose body matches bodyHash in metadata, or the first update of incidents registered by older versions.
+// Updates added by Statuspage (e.g. in progress and completed) are ignored.
+func (incident StatuspageIncident) body() string {
+	u := incident.bodyUpdate()
+	if u == nil {
+		return ""
+	}
+	return u.Body
+}
+
+// return the update of body written by this tool. nil is returned if it's not found. see body
+func (incident StatuspageIncident) bodyUpdate() *StatuspageIncidentUpdateEntry {
+	hash := incident.bodyHash()
+	var found *StatuspageIncidentUpdateEntry
+	for idx, u := range incident.IncidentUpdates {
+		if hash != "" && bodyHashOf(u.Body) != hash {
+			continue
+		}
+		if found == nil ||
+			(hash != "" && u.CreatedAt.After(found.CreatedAt)) ||
+			(hash == "" && u.CreatedAt.Before(found.CreatedAt)) {
+			found = &incident.IncidentUpdates[idx]
+		}
+	}
+	return found
+}
+
+// return true if the body written by this tool is the same as body.
+// When bodyHash is written, the update of the body should also remain, or the body was removed in console.
+func (incident StatuspageIncident) hasBody(body string) bool {
+	if hash := incident.bodyHash(); hash != "" {
+		return hash == bodyHashOf(body) && incident.bodyUpdate() != nil
+	}
+	return incident.body() == body
+}
+
+// return bodyHash written in metadata by this tool. Empty string is returned if it's not found.
+func (incident StatuspageIncident) bodyHash() string {
+	data, ok := incident.Metadata[key_toolNamespace]
+	if !ok {
+		return ""
+	}
+	hash, _ := data[key_bodyHash].(string)
+	return hash
+}
+
+// return hash of body written in metadata, so that the body is compared with the body written by this tool
+func bodyHashOf(body string) string {
+	hash := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(hash[:8])
+}
+
+func (incident StatuspageIncident) componentIds() []string {
+	ids := make([]string, 0, len(incident.Components))
+	for _, c := range incident.Components {
+		ids = append(ids, c.Id)
+	}
+	return ids
+}
+
 // return true if StatuspageIncident is scheduled by this tool and is recurring schedule
 func (incident StatuspageIncident) isRecurringSchedule() bool {
 	data, ok := incident.Metadata[key_toolNamespace]
@@ -86,24 +189,84 @@ func (incident StatuspageIncident) isRecurringSchedule() bool {
 	}
 }
 
-// return true if StatuspageIncident has ids components
-func (incident StatuspageIncident) isSameComponentIds(ids []string) bool {
-	if len(ids) != len(incident.Components) {
+// return true if the maintenance has not started yet.
+// Incidents without status are treated as scheduled.
+func (incident StatuspageIncident) isScheduled() bool {
+	return incident.Status == "" || incident.Status == IncidentStatus_Scheduled
+}
+
+// return true if the window of maintenance has started and is not completed
+func (incident StatuspageIncident) isInProgress() bool {
+	return incident.Status == IncidentStatus_InProgress || incident.Status == IncidentStatus_Verifying
+}
+
+// return true if StatuspageIncident is created by the owner.
+// Incidents created by older versions are owned by the empty owner.
+func (incident StatuspageIncident) isOwnedBy(owner string) bool {
+	data, ok := incident.Metadata[key_toolNamespace]
+	if !ok {
 		return false
 	}
+	o, _ := data[key_owner].(string)
+	return o == owner
+}
 
-	for _, c := range incident.Components {
-		found := false
-		for _, id := range ids {
+// return true if StatuspageIncident is registered by this tool, including those of other owners and once command
+func (incident StatuspageIncident) isRegisteredByTool() bool {
+	_, ok := incident.Metadata[key_toolNamespace]
+	return ok
+}
+
+// return true if StatuspageIncident is registered by once command
+func (incident StatuspageIncident) isOneoffSchedule() bool {
+	return incident.scheduleType() == ScheduleType_Oneoff
+}
+
+// return scheduleType written in metadata by this tool. Empty string is returned if it's not found.
+func (incident StatuspageIncident) scheduleType() string {
+	data, ok := incident.Metadata[key_toolNamespace]
+	if !ok {
+		return ""
+	}
+	t, _ := data[key_scheduleType].(string)
+	return t
+}
+
+// return scheduleKey written in metadata by this tool. Empty string is returned if it's not found.
+func (incident StatuspageIncident) scheduleKey() string {
+	data, ok := incident.Metadata[key_toolNamespace]
+	if !ok {
+		return ""
+	}
+	key, _ := data[key_scheduleKey].(string)
+	return key
+}
+
+// return true if StatuspageIncident has ids components. ids are compared as a set.
+func (incident StatuspageIncident) isSameComponentIds(ids []string) bool {
+	missing, extra := diffComponentIds(incident.componentIds(), ids)
+	return len(missing) == 0 && len(extra) == 0
+}
+
+// return ids in the components of StatuspageIncident, in order of ids
+func (incident StatuspageIncident) intersectComponentIds(ids []string) []string {
+	result := make([]string, 0)
+	for _, id := range ids {
+		for _, c := range incident.Components {
 			if c.Id == id {
-				found = true
+				result = append(result, id)
+				break
 			}
 		}
-		if !found {
-			return false
-		}
 	}
-	return true
+	return result
+}
+
+// return key of components and term of StatuspageIncident. components are compared as a set.
+func (incident StatuspageIncident) termKey() string {
+	ids := incident.componentIds()
+	sort.Strings(ids)
+	return fmt.Sprintf("%s/%d/%d", strings.Join(ids, ","), incident.ScheduledFor.Unix(), incident.ScheduledUntil.Unix())
 }
 
 func (incident StatuspageIncident) isSameTerm(start time.Time, end time.Time) bool {
@@ -126,41 +289,145 @@ type StatuspageComponnet struct {
 	AutomationEmail    string    `json:"automation_email"`
 }
 
+// return updated fields as string
+func (u StatuspageIncidentUpdate) String() string {
+	fields := make([]string, 0)
+	if u.Name != "" {
+		fields = append(fields, fmt.Sprintf("name:%q", u.Name))
+	}
+	if u.Body != "" {
+		fields = append(fields, fmt.Sprintf("body:%q", u.Body))
+	}
+	if u.ScheduledFor != nil {
+		fields = append(fields, fmt.Sprintf("scheduled_for:%s", u.ScheduledFor))
+	}
+	if u.ScheduledUntil != nil {
+		fields = append(fields, fmt.Sprintf("scheduled_until:%s", u.ScheduledUntil))
+	}
+	if u.ComponentIds != nil {
+		fields = append(fields, fmt.Sprintf("component_ids:%v", u.ComponentIds))
+	}
+	return strings.Join(fields, " ")
+}
+
+// Options of scheduled maintenance.
+// nil (or empty string) means that the value is taken from defaults.
+type MaintenanceOptions struct {
+	Impact                             string `yaml:"impact" json:"impact,omitempty"` // none, maintenance, minor, major or critical
+	DeliverNotifications               *bool  `yaml:"deliverNotifications" json:"deliverNotifications,omitempty"`
+	RemindPrior                        *bool  `yaml:"remindPrior" json:"remindPrior,omitempty"`
+	AutoInProgress                     *bool  `yaml:"autoInProgress" json:"autoInProgress,omitempty"`
+	AutoCompleted                      *bool  `yaml:"autoCompleted" json:"autoCompleted,omitempty"`
+	AutoTransitionNotificationsAtStart *bool  `yaml:"autoTransitionNotificationsAtStart" json:"autoTransitionNotificationsAtStart,omitempty"`
+	AutoTransitionNotificationsAtEnd   *bool  `yaml:"autoTransitionNotificationsAtEnd" json:"autoTransitionNotificationsAtEnd,omitempty"`
+	AutoTransitionToMaintenanceState   *bool  `yaml:"autoTransitionToMaintenanceState" json:"autoTransitionToMaintenanceState,omitempty"`
+	AutoTransitionToOperationalState   *bool  `yaml:"autoTransitionToOperationalState" json:"autoTransitionToOperationalState,omitempty"`
+	AutoTweetOnCreation                *bool  `yaml:"autoTweetOnCreation" json:"autoTweetOnCreation,omitempty"`
+	AutoTweetOneHourBefore             *bool  `yaml:"autoTweetOneHourBefore" json:"autoTweetOneHourBefore,omitempty"`
+	AutoTweetAtBeginning               *bool  `yaml:"autoTweetAtBeginning" json:"autoTweetAtBeginning,omitempty"`
+	AutoTweetOnCompletion              *bool  `yaml:"autoTweetOnCompletion" json:"autoTweetOnCompletion,omitempty"`
+}
+
+var maintenanceImpacts = []string{"none", "maintenance", "minor", "major", "critical"}
+
+// return options which are used when no option is specified
+func defaultMaintenanceOptions() MaintenanceOptions {
+	on, off := true, false
+	return MaintenanceOptions{
+		Impact:                             "maintenance",
+		DeliverNotifications:               &on,
+		RemindPrior:                        &on,
+		AutoInProgress:                     &on,
+		AutoCompleted:                      &on,
+		AutoTransitionNotificationsAtStart: &on,
+		AutoTransitionNotificationsAtEnd:   &on,
+		AutoTransitionToMaintenanceState:   &on,
+		AutoTransitionToOperationalState:   &on,
+		AutoTweetOnCreation:                &off,
+		AutoTweetOneHourBefore:             &off,
+		AutoTweetAtBeginning:               &off,
+		AutoTweetOnCompletion:              &off,
+	}
+}
+
+// return options whose unspecified values are filled by defaults
+func (o MaintenanceOptions) merge(defaults MaintenanceOptions) MaintenanceOptions {
+	if o.Impact == "" {
+		o.Impact = defaults.Impact
+	}
+	mergeBool := func(value **bool, defaultValue *bool) {
+		if *value == nil {
+			*value = defaultValue
+		}
+	}
+	mergeBool(&o.DeliverNotifications, defaults.DeliverNotifications)
+	mergeBool(&o.RemindPrior, defaults.RemindPrior)
+	mergeBool(&o.AutoInProgress, defaults.AutoInProgress)
+	mergeBool(&o.AutoCompleted, defaults.AutoCompleted)
+	mergeBool(&o.AutoTransitionNotificationsAtStart, defaults.AutoTransitionNotificationsAtStart)
+	mergeBool(&o.AutoTransitionNotificationsAtEnd, defaults.AutoTransitionNotificationsAtEnd)
+	mergeBool(&o.AutoTransitionToMaintenanceState, defaults.AutoTransitionToMaintenanceState)
+	mergeBool(&o.AutoTransitionToOperationalState, defaults.AutoTransitionToOperationalState)
+	mergeBool(&o.AutoTweetOnCreation, defaults.AutoTweetOnCreation)
+	mergeBool(&o.AutoTweetOneHourBefore, defaults.AutoTweetOneHourBefore)
+	mergeBool(&o.AutoTweetAtBeginning, defaults.AutoTweetAtBeginning)
+	mergeBool(&o.AutoTweetOnCompletion, defaults.AutoTweetOnCompletion)
+	return o
+}
+
+func (o MaintenanceOptions) validate() error {
+	if o.Impact == "" {
+		return nil
+	}
+	for _, impact := range maintenanceImpacts {
+		if o.Impact == impact {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid impact: %s", o.Impact)
+}
+
 func CreateMaintenanceStatuspageData(
 	title string,
 	body string,
 	componentIds []string,
 	start time.Time,
 	end time.Time,
+	options MaintenanceOptions,
 	scheduleType string,
 	scheduleKey string,
+	owner string,
 ) StatuspageCreateIncidentRequest {
+	options = options.merge(defaultMaintenanceOptions())
+
 	return StatuspageCreateIncidentRequest{
 		Incident: StatuspageIncidentRequest{
 			Name:                    title,
 			Status:                  "scheduled",
-			ImpactOverride:          "maintenance",
+			ImpactOverride:          options.Impact,
 			ScheduledFor:            start,
 			ScheduledUntil:          end,
-			ScheduledRemindPrior:    true,
-			ScheduledAutoInProgress: true,
-			ScheduledAutoCompleted:  true,
+			ScheduledRemindPrior:    *options.RemindPrior,
+			ScheduledAutoInProgress: *options.AutoInProgress,
+			ScheduledAutoCompleted:  *options.AutoCompleted,
 			Metadata: map[string]interface{}{
 				key_toolNamespace: map[string]interface{}{
 					"createdAt":      time.Now(),
 					key_scheduleType: scheduleType,
 					key_scheduleKey:  scheduleKey,
+					key_owner:        owner,
+					key_bodyHash:     bodyHashOf(body),
 				},
 			},
-			DeliverNotifications:                      true,
-			AutoTransitionDeliverNotificationsAtEnd:   true,
-			AutoTransitionDeliverNotificationsAtStart: true,
-			AutoTransitionToMaintenanceState:          true,
-			AutoTransitionToOperationalState:          true,
-			AutoTweetAtBeginning:                      false,
-			AutoTweetOnCompletion:                     false,
-			AutoTweetOnCreation:                       false,
-			AutoTweetOneHourBefore:                    false,
+			DeliverNotifications:                      *options.DeliverNotifications,
+			AutoTransitionDeliverNotificationsAtEnd:   *options.AutoTransitionNotificationsAtEnd,
+			AutoTransitionDeliverNotificationsAtStart: *options.AutoTransitionNotificationsAtStart,
+			AutoTransitionToMaintenanceState:          *options.AutoTransitionToMaintenanceState,
+			AutoTransitionToOperationalState:          *options.AutoTransitionToOperationalState,
+			AutoTweetAtBeginning:                      *options.AutoTweetAtBeginning,
+			AutoTweetOnCompletion:                     *options.AutoTweetOnCompletion,
+			AutoTweetOnCreation:                       *options.AutoTweetOnCreation,
+			AutoTweetOneHourBefore:                    *options.AutoTweetOneHourBefore,
 			Backfilled:                                false,
 			Body:                                      body,
 			Components:                                map[string]interface{}{},
@@ -170,10 +437,31 @@ func CreateMaintenanceStatuspageData(
 	}
 }
 
+// return all components of the page, following pagination
+func findAllComponents(repository StatuspageRepository) ([]StatuspageComponnet, error) {
+	const perPage = 100
+
+	components := make([]StatuspageComponnet, 0)
+	for page := 1; ; page++ {
+		c, err := repository.FindAllComponents(page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, c...)
+		if len(c) < perPage {
+			return components, nil
+		}
+	}
+}
+
 type StatuspageRepository interface {
-	Add(data StatuspageCreateIncidentRequest) error
+	Add(data StatuspageCreateIncidentRequest) (StatuspageIncident, error) // return created incident
 	FindAllScheduledIncidents(page int, perPage int) ([]StatuspageIncident, error)
+	FindAllActiveMaintenances(page int, perPage int) ([]StatuspageIncident, error)
+	FindIncident(incidentId string) (*StatuspageIncident, error) // return nil if it's not found
 	Delete(incident StatuspageIncident) error
+	Update(incident StatuspageIncident, data StatuspageUpdateIncidentRequest) error
+	FindAllComponents(page int, perPage int) ([]StatuspageComponnet, error)
 }
 
 // DryRun Repository
@@ -187,13 +475,15 @@ func createStatuspageDryRunRepository(pageId string, accessToken string) *Status
 	}
 }
 
-func (s *StatuspageDryRunRepository) Add(data StatuspageCreateIncidentRequest) error {
-	fmt.Printf("[dryRun]: add [%s] %s - %s\n", data.Incident.Name, data.Incident.ScheduledFor, data.Incident.ScheduledUntil)
-	return nil
+func (s *StatuspageDryRunRepository) Add(data StatuspageCreateIncidentRequest) (StatuspageIncident, error) {
+	return StatuspageIncident{}, nil
 }
 
 func (s *StatuspageDryRunRepository) Delete(incident StatuspageIncident) error {
-	fmt.Printf("[dryRun]: delete [%s] %s - %s id:%s\n", incident.Name, incident.ScheduledFor, incident.ScheduledUntil, incident.Id)
+	return nil
+}
+
+func (s *StatuspageDryRunRepository) Update(incident StatuspageIncident, data StatuspageUpdateIncidentRequest) error {
 	return nil
 }
 
@@ -201,6 +491,18 @@ func (s *StatuspageDryRunRepository) FindAllScheduledIncidents(page int, perPage
 	return s.statuspageRESTClient.FindAllScheduledIncidents(page, perPage)
 }
 
+func (s *StatuspageDryRunRepository) FindAllActiveMaintenances(page int, perPage int) ([]StatuspageIncident, error) {
+	return s.statuspageRESTClient.FindAllActiveMaintenances(page, perPage)
+}
+
+func (s *StatuspageDryRunRepository) FindIncident(incidentId string) (*StatuspageIncident, error) {
+	return s.statuspageRESTClient.FindIncident(incidentId)
+}
+
+func (s *StatuspageDryRunRepository) FindAllComponents(page int, perPage int) ([]StatuspageComponnet, error) {
+	return s.statuspageRESTClient.FindAllComponents(page, perPage)
+}
+
 // REST Repository
 type StatuspageRESTRepository struct {
 	statuspageRESTClient StatuspageRESTClient
@@ -212,104 +514,182 @@ func createStatuspageRESTRepository(pageId string, accessToken string) *Statuspa
 	}
 }
 
-func (s *StatuspageRESTRepository) Add(data StatuspageCreateIncidentRequest) error {
-	fmt.Printf("add [%s] %s - %s\n", data.Incident.Name, data.Incident.ScheduledFor, data.Incident.ScheduledUntil)
+func (s *StatuspageRESTRepository) Add(data StatuspageCreateIncidentRequest) (StatuspageIncident, error) {
 	return s.statuspageRESTClient.Add(data)
 }
 
 func (s *StatuspageRESTRepository) Delete(incident StatuspageIncident) error {
-	fmt.Printf("[dryRun]: delete [%s] %s - %s id:%s\n", incident.Name, incident.ScheduledFor, incident.ScheduledUntil, incident.Id)
 	return s.statuspageRESTClient.Delete(incident.Id)
 }
 
+func (s *StatuspageRESTRepository) Update(incident StatuspageIncident, data StatuspageUpdateIncidentRequest) error {
+	return s.statuspageRESTClient.Update(incident.Id, data)
+}
+
 func (s *StatuspageRESTRepository) FindAllScheduledIncidents(page int, perPage int) ([]StatuspageIncident, error) {
 	return s.statuspageRESTClient.FindAllScheduledIncidents(page, perPage)
 }
 
+func (s *StatuspageRESTRepository) FindAllActiveMaintenances(page int, perPage int) ([]StatuspageIncident, error) {
+	return s.statuspageRESTClient.FindAllActiveMaintenances(page, perPage)
+}
+
+func (s *StatuspageRESTRepository) FindIncident(incidentId string) (*StatuspageIncident, error) {
+	return s.statuspageRESTClient.FindIncident(incidentId)
+}
+
+func (s *StatuspageRESTRepository) FindAllComponents(page int, perPage int) ([]StatuspageComponnet, error) {
+	return s.statuspageRESTClient.FindAllComponents(page, perPage)
+}
+
+// endpoints of Statuspage API, which are labels of metrics
+const (
+	endpoint_Incidents          = "incidents"
+	endpoint_Incident           = "incidents/{id}"
+	endpoint_ScheduledIncidents = "incidents/scheduled"
+	endpoint_ActiveMaintenances = "incidents/active_maintenance"
+	endpoint_Components         = "components"
+)
+
 // StatuspageRESTClient
 type StatuspageRESTClient struct {
 	PageId      string
 	AccessToken string
 }
 
-func (s *StatuspageRESTClient) Add(data StatuspageCreateIncidentRequest) error {
-	url := fmt.Sprintf("https://api.statuspage.io/v1/pages/%s/incidents", s.PageId)
-	bearer := "OAuth " + s.AccessToken
+const statuspageAPIBaseUrl = "https://api.statuspage.io/v1"
 
-	body, _ := json.Marshal(&data)
+func (s *StatuspageRESTClient) Add(data StatuspageCreateIncidentRequest) (StatuspageIncident, error) {
+	url := fmt.Sprintf("%s/pages/%s/incidents", statuspageAPIBaseUrl, s.PageId)
 
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	req.Header.Set("Authorization", bearer)
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	res, err := client.Do(req)
+	var incident StatuspageIncident
+	body, err := json.Marshal(&data)
 	if err != nil {
-		return err
+		return incident, err
 	}
-	defer res.Body.Close()
 
-	respBody, err := ioutil.ReadAll(res.Body)
+	respBody, err := s.request("POST", endpoint_Incidents, url, body, http.StatusCreated)
 	if err != nil {
-		return err
+		return incident, err
 	}
-	if res.StatusCode != 201 {
-		return errors.New(string(respBody))
+	if err := json.Unmarshal(respBody, &incident); err != nil {
+		return incident, &APIError{Method: "POST", Url: url, Err: err}
 	}
-
-	return nil
+	return incident, nil
 }
 
 func (s *StatuspageRESTClient) Delete(incidentId string) error {
-	url := fmt.Sprintf("https://api.statuspage.io/v1/pages/%s/incidents/%s", s.PageId, incidentId)
-	bearer := "OAuth " + s.AccessToken
+	url := fmt.Sprintf("%s/pages/%s/incidents/%s", statuspageAPIBaseUrl, s.PageId, incidentId)
 
-	req, _ := http.NewRequest("DELETE", url, nil)
-	req.Header.Set("Authorization", bearer)
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
+	_, err := s.request("DELETE", endpoint_Incident, url, nil, http.StatusOK)
+	return err
+}
 
-	respBody, err := ioutil.ReadAll(res.Body)
+func (s *StatuspageRESTClient) Update(incidentId string, data StatuspageUpdateIncidentRequest) error {
+	url := fmt.Sprintf("%s/pages/%s/incidents/%s", statuspageAPIBaseUrl, s.PageId, incidentId)
+
+	body, err := json.Marshal(&data)
 	if err != nil {
 		return err
 	}
-	if res.StatusCode != 200 {
-		return errors.New(string(respBody))
-	}
 
-	return nil
+	_, err = s.request("PATCH", endpoint_Incident, url, body, http.StatusOK)
+	return err
 }
 
 func (s *StatuspageRESTClient) FindAllScheduledIncidents(page int, perPage int) ([]StatuspageIncident, error) {
-	url := fmt.Sprintf("https://api.statuspage.io/v1/pages/%s/incidents/scheduled?page=%d&per_page=%d", s.PageId, page, perPage)
-	bearer := "OAuth " + s.AccessToken
+	url := fmt.Sprintf("%s/pages/%s/incidents/scheduled?page=%d&per_page=%d", statuspageAPIBaseUrl, s.PageId, page, perPage)
+	return s.findIncidents(endpoint_ScheduledIncidents, url)
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", bearer)
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	res, err := client.Do(req)
+// return maintenances which are in progress or verifying
+func (s *StatuspageRESTClient) FindAllActiveMaintenances(page int, perPage int) ([]StatuspageIncident, error) {
+	url := fmt.Sprintf("%s/pages/%s/incidents/active_maintenance?page=%d&per_page=%d", statuspageAPIBaseUrl, s.PageId, page, perPage)
+	return s.findIncidents(endpoint_ActiveMaintenances, url)
+}
+
+func (s *StatuspageRESTClient) FindIncident(incidentId string) (*StatuspageIncident, error) {
+	url := fmt.Sprintf("%s/pages/%s/incidents/%s", statuspageAPIBaseUrl, s.PageId, incidentId)
+
+	body, err := s.request("GET", endpoint_Incident, url, nil, http.StatusOK)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	body, err := ioutil.ReadAll(res.Body)
+	var incident StatuspageIncident
+	if err := json.Unmarshal(body, &incident); err != nil {
+		return nil, &APIError{Method: "GET", Url: url, Err: err}
+	}
+	return &incident, nil
+}
+
+func (s *StatuspageRESTClient) findIncidents(endpoint string, url string) ([]StatuspageIncident, error) {
+	body, err := s.request("GET", endpoint, url, nil, http.StatusOK)
 	if err != nil {
 		return nil, err
 	}
-	if res.StatusCode != 200 {
-		return nil, errors.New(string(body))
-	}
 
 	var incidents []StatuspageIncident
 	err = json.Unmarshal(body, &incidents)
 	if err != nil {
-		return nil, err
+		return nil, &APIError{Method: "GET", Url: url, Err: err}
 	}
 	return incidents, nil
 }
+
+func (s *StatuspageRESTClient) FindAllComponents(page int, perPage int) ([]StatuspageComponnet, error) {
+	url := fmt.Sprintf("%s/pages/%s/components?page=%d&per_page=%d", statuspageAPIBaseUrl, s.PageId, page, perPage)
+
+	body, err := s.request("GET", endpoint_Components, url, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []StatuspageComponnet
+	err = json.Unmarshal(body, &components)
+	if err != nil {
+		return nil, &APIError{Method: "GET", Url: url, Err: err}
+	}
+	return components, nil
+}
+
+// send request to Statuspage API, and return body of the response.
+// APIError is returned when the status code of the response is not expectedStatus.
+// endpoint is recorded in metrics instead of url which contains ids.
+func (s *StatuspageRESTClient) request(method string, endpoint string, url string, body []byte, expectedStatus int) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewBuffer(body)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, &APIError{Method: method, Url: url, Err: err}
+	}
+	req.Header.Set("Authorization", "OAuth "+s.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		observeAPIRequest(method, endpoint, "error", start)
+		return nil, &APIError{Method: method, Url: url, Err: err}
+	}
+	defer res.Body.Close()
+	observeAPIRequest(method, endpoint, strconv.Itoa(res.StatusCode), start)
+
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, &APIError{Method: method, Url: url, StatusCode: res.StatusCode, Err: err}
+	}
+	if res.StatusCode != expectedStatus {
+		return nil, &APIError{Method: method, Url: url, StatusCode: res.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
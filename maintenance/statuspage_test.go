@@ -0,0 +1,39 @@
+package maintenance
+
+import (
+	"testing"
+)
+
+func TestCreateMaintenanceStatuspageDataOptions(t *testing.T) {
+	on, off := true, false
+
+	pageDefaults := MaintenanceOptions{
+		DeliverNotifications: &off,
+		AutoTweetOnCreation:  &on,
+	}
+	options := MaintenanceOptions{
+		Impact:              "minor",
+		AutoTweetOnCreation: &off,
+	}
+
+	data := CreateMaintenanceStatuspageData(
+		"title", "body", []string{"c1"},
+		timeOf(2020, 1, 1, 1, 0), timeOf(2020, 1, 1, 2, 0),
+		options.merge(pageDefaults),
+		ScheduleType_Recurring, "", "",
+	)
+
+	incident := data.Incident
+	if incident.ImpactOverride != "minor" {
+		t.Errorf("ImpactOverride: exp:minor, actual:%v", incident.ImpactOverride)
+	}
+	if incident.DeliverNotifications {
+		t.Errorf("DeliverNotifications should be taken from page defaults")
+	}
+	if incident.AutoTweetOnCreation {
+		t.Errorf("AutoTweetOnCreation of maintenance should override page defaults")
+	}
+	if !incident.ScheduledRemindPrior || !incident.AutoTransitionToMaintenanceState {
+		t.Errorf("unspecified options should be default values")
+	}
+}
@@ -0,0 +1,84 @@
+package maintenance
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Variables which can be used in title and body of maintenance
+//
+// e.g. "Maintenance of {{.Description}} ({{.Ordinal}} of {{.Count}})"
+// e.g. "until {{(in \"America/Los_Angeles\" .End).Format \"15:04 MST\"}}"
+type TemplateData struct {
+	Service     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	StartUTC    time.Time
+	EndUTC      time.Time
+	Duration    time.Duration
+	Ordinal     int      // ordinal of the occurrence in the calendar month, starting from 1
+	Count       int      // count of occurrences of the service in the calendar month
+	Components  []string // names of components
+
+	// Locale of the text, and the window of maintenance in time zone and format of the locale
+	Locale     string
+	LocalStart time.Time
+	LocalEnd   time.Time
+	Window     string
+}
+
+var templateFuncs = template.FuncMap{
+	// return t in the time zone. e.g. {{in "America/Los_Angeles" .Start}}
+	"in": func(name string, t time.Time) (time.Time, error) {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return t, err
+		}
+		return t.In(l), nil
+	},
+	"join": strings.Join,
+}
+
+func parseTemplate(name string, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template of %s: %w", name, err)
+	}
+	return t, nil
+}
+
+func renderTemplate(name string, text string, data TemplateData) (string, error) {
+	t, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template of %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// return error if text can't be rendered.
+// the template is rendered with sample data to find references to unknown variables.
+func validateTemplate(name string, text string) error {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, loc)
+	_, err := renderTemplate(name, text, TemplateData{
+		Service:     "service",
+		Description: "description",
+		Start:       start,
+		End:         start.Add(time.Hour),
+		StartUTC:    start.UTC(),
+		EndUTC:      start.Add(time.Hour).UTC(),
+		Duration:    time.Hour,
+		Ordinal:     1,
+		Count:       1,
+		Components:  []string{"component"},
+	})
+	return err
+}